@@ -6,10 +6,19 @@ import (
 
 	"my-telegram-bot/pkg/auth"
 	"my-telegram-bot/pkg/bot"
+	"my-telegram-bot/pkg/geo"
 )
 
 func main() {
 	YOUR_TELEGRAM_BOT_TOKEN := ""
+	// Leave empty to place orders without the Telegram Payments step
+	YOUR_PAYMENT_PROVIDER_TOKEN := ""
+	// Leave empty to resolve shared locations with the sample gazetteer bundled with the bot
+	YOUR_GAZETTEER_PATH := ""
+	// Leave empty to deliver to any address
+	YOUR_DELIVERY_ZONES_PATH := ""
+	// Leave empty to load the catalog for the search with the credentials of the first user who searches
+	YOUR_CATALOG_API_TOKEN := ""
 
 	apiClient := api.NewAPIClient("http://127.0.0.1:8000/api")
 	authClient := auth.NewAuthClient()
@@ -19,6 +28,26 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize bot: %v", err)
 	}
+	if YOUR_PAYMENT_PROVIDER_TOKEN != "" {
+		bot.EnablePayments(YOUR_PAYMENT_PROVIDER_TOKEN, "USD")
+	}
+	if YOUR_GAZETTEER_PATH != "" {
+		gazetteer, err := geo.LoadGazetteer(YOUR_GAZETTEER_PATH)
+		if err != nil {
+			log.Fatalf("Failed to load gazetteer: %v", err)
+		}
+		bot.SetGeocoder(gazetteer)
+	}
+	if YOUR_DELIVERY_ZONES_PATH != "" {
+		zones, err := geo.LoadZones(YOUR_DELIVERY_ZONES_PATH)
+		if err != nil {
+			log.Fatalf("Failed to load delivery zones: %v", err)
+		}
+		bot.SetDeliveryZones(zones)
+	}
+	if YOUR_CATALOG_API_TOKEN != "" {
+		bot.SetCatalogToken(YOUR_CATALOG_API_TOKEN)
+	}
 
 	bot.Run()
 }
@@ -11,6 +11,7 @@ import (
 	"my-telegram-bot/pkg/auth"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -21,6 +22,7 @@ func NewAPIClient(baseURL string) *APIClient {
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		catalogCache: newCatalogCache(DefaultCatalogCacheTTL),
 	}
 }
 
@@ -51,6 +53,18 @@ func (api *APIClient) Register(data RegisterData, chatID int64, authClient *auth
 	if data.LastName != "" {
 		fields["last_name"] = data.LastName
 	}
+	// Send the structured address along with the formatted one
+	if details := data.AddressDetails; details != nil {
+		fields["street"] = details.Street
+		fields["building"] = details.Building
+		fields["apartment"] = details.Apartment
+		fields["city"] = details.City
+		fields["postcode"] = details.Postcode
+		if details.HasCoordinates() {
+			fields["latitude"] = strconv.FormatFloat(details.Latitude, 'f', 6, 64)
+			fields["longitude"] = strconv.FormatFloat(details.Longitude, 'f', 6, 64)
+		}
+	}
 
 	// Add other form fields
 	for key, value := range fields {
@@ -142,6 +156,9 @@ func (api *APIClient) decodeResponse(resp *http.Response, result interface{}) er
 			}
 			return &Error{Err: errors.New("Validation error"), Message: "Validation error", Details: &ve}
 		}
+		if resp.StatusCode == http.StatusNotFound {
+			return &Error{Err: ErrNotFound, Message: "Not found"}
+		}
 		return &Error{Err: errors.New(string(bodyBytes)), Message: "API error"}
 	}
 
@@ -187,6 +204,11 @@ func (api *APIClient) Login(data LoginData) (string, error) {
 
 // makeAPIRequest creates and sends an API request. If the token is expired, it refreshes the token and retries.
 func (api *APIClient) makeAPIRequest(method, url string, body io.Reader, authClient *auth.AuthClient, chatID int64, contentType ...string) (*http.Response, error) {
+	return api.makeAPIRequestWithHeader(method, url, body, authClient, chatID, nil, contentType...)
+}
+
+// makeAPIRequestWithHeader works like makeAPIRequest and adds the given headers to the request.
+func (api *APIClient) makeAPIRequestWithHeader(method, url string, body io.Reader, authClient *auth.AuthClient, chatID int64, header http.Header, contentType ...string) (*http.Response, error) {
 	defaultContentType := "application/json"
 
 	// Convert the io.Reader content to a byte slice
@@ -211,6 +233,11 @@ func (api *APIClient) makeAPIRequest(method, url string, body io.Reader, authCli
 		}
 
 		req.Header.Add("Accept", "application/json")
+		for key, values := range header {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
 
 		response, err := api.client.Do(req)
 		if err != nil {
@@ -242,25 +269,13 @@ func readerToBytes(reader io.Reader) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// GetProducts fetches a list of products with pagination. It also updates the 'InCart' field for each product based on the items in the cart.
-func (api *APIClient) GetProducts(perPage int, page int, authClient *auth.AuthClient, chatID int64, search string) ([]Product, bool, error) {
-
-	urlStr := fmt.Sprintf("%s/products?per_page=%d&page=%d", api.BaseURL, perPage, page)
-	if search != "" {
-		urlStr = fmt.Sprintf("%s&search=%s", urlStr, url.QueryEscape(search))
-	}
-	resp, err := api.makeAPIRequest("", urlStr, nil, authClient, chatID)
+// GetProducts fetches a page of the products matching the query. It also updates the 'InCart' field for each product based on the items in the cart.
+func (api *APIClient) GetProducts(query ProductQuery, authClient *auth.AuthClient, chatID int64) ([]Product, bool, error) {
+	products, hasNextPage, err := api.ListProducts(query, authClient, chatID)
 	if err != nil {
 		return nil, false, err
 	}
 
-	var productsResponse ProductsResponse
-	if err := api.decodeResponse(resp, &productsResponse); err != nil {
-		return nil, false, err
-	}
-
-	next := productsResponse.Links["next"]
-
 	// Get the cart items
 	cartItems, err := api.GetCartItems(authClient, false, chatID)
 
@@ -275,17 +290,78 @@ func (api *APIClient) GetProducts(perPage int, page int, authClient *auth.AuthCl
 	}
 
 	// Update the products with the number of items in the cart
-	for i, product := range productsResponse.Data {
+	for i, product := range products {
 		if quantity, ok := cartItemsMap[product.ID]; ok {
-			productsResponse.Data[i].InCart = quantity
+			products[i].InCart = quantity
 		} else {
-			productsResponse.Data[i].InCart = 0
+			products[i].InCart = 0
 		}
 	}
 
+	return products, hasNextPage, nil
+}
+
+// ListProducts fetches a page of the products matching the query as the catalog has them, without the cart quantities.
+// It returns whether there is a next page.
+func (api *APIClient) ListProducts(query ProductQuery, authClient *auth.AuthClient, chatID int64) ([]Product, bool, error) {
+	params := url.Values{}
+	params.Set("per_page", strconv.Itoa(query.PerPage))
+	params.Set("page", strconv.Itoa(query.Page))
+	if query.Search != "" {
+		params.Set("search", query.Search)
+	}
+	if query.CategoryID > 0 {
+		params.Set("category_id", strconv.Itoa(query.CategoryID))
+	}
+	if query.Sort != "" {
+		params.Set("sort", query.Sort)
+	}
+	if query.MinPrice > 0 {
+		params.Set("min_price", strconv.FormatFloat(query.MinPrice, 'f', -1, 64))
+	}
+	if query.MaxPrice > 0 {
+		params.Set("max_price", strconv.FormatFloat(query.MaxPrice, 'f', -1, 64))
+	}
+	if query.MinWeight > 0 {
+		params.Set("min_weight", strconv.Itoa(query.MinWeight))
+	}
+	if query.MaxWeight > 0 {
+		params.Set("max_weight", strconv.Itoa(query.MaxWeight))
+	}
+	if query.InStock {
+		params.Set("in_stock", "1")
+	}
+
+	urlStr := api.BaseURL + "/products?" + params.Encode()
+	var productsResponse ProductsResponse
+	if err := api.getCatalog(urlStr, authClient, chatID, &productsResponse); err != nil {
+		return nil, false, err
+	}
+
+	next := productsResponse.Links["next"]
 	return productsResponse.Data, next != nil, nil
 }
 
+// GetCategories fetches the whole category tree as a flat list, each category pointing to its parent.
+func (api *APIClient) GetCategories(authClient *auth.AuthClient, chatID int64) ([]Category, error) {
+	url := fmt.Sprintf("%s/categories", api.BaseURL)
+	var categoriesResponse CategoriesResponse
+	if err := api.getCatalog(url, authClient, chatID, &categoriesResponse); err != nil {
+		return nil, err
+	}
+	return categoriesResponse.Data, nil
+}
+
+// GetProduct fetches a single product with all its images and the related products.
+func (api *APIClient) GetProduct(productID int, authClient *auth.AuthClient, chatID int64) (*Product, error) {
+	url := fmt.Sprintf("%s/products/%d", api.BaseURL, productID)
+	var productResponse ProductResponse
+	if err := api.getCatalog(url, authClient, chatID, &productResponse); err != nil {
+		return nil, err
+	}
+	return &productResponse.Data, nil
+}
+
 // GetCartItems fetches items in the cart. If showNames is true, it also fetches the names and prices of the products.
 func (api *APIClient) GetCartItems(authClient *auth.AuthClient, showNames bool, chatID int64) ([]CartItem, error) {
 	url := fmt.Sprintf("%s/cart?showNamesAndPrices=%t", api.BaseURL, showNames)
@@ -340,6 +416,44 @@ func (api *APIClient) RemoveProductFromCart(productID int, authClient *auth.Auth
 	return nil
 }
 
+// SetCartItemQuantity sets the quantity of a product in the cart, replacing the previous quantity.
+func (api *APIClient) SetCartItemQuantity(productID, quantity int, authClient *auth.AuthClient, chatID int64) error {
+	url := fmt.Sprintf("%s/cart/%d", api.BaseURL, productID)
+
+	jsonData, err := json.Marshal(map[string]int{"quantity": quantity})
+	if err != nil {
+		return &Error{Err: err, Message: "Failed to json encode"}
+	}
+	resp, err := api.makeAPIRequest(http.MethodPut, url, bytes.NewBuffer(jsonData), authClient, chatID)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return api.decodeResponse(resp, nil)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// ValidateCart asks the backend to check that every item in the cart can still be ordered.
+// A cart that cannot be ordered is reported as an error with the reason returned by the API.
+// It returns an error wrapping ErrNotFound when the backend has no validation endpoint.
+func (api *APIClient) ValidateCart(authClient *auth.AuthClient, chatID int64) error {
+	url := fmt.Sprintf("%s/cart/validate", api.BaseURL)
+
+	resp, err := api.makeAPIRequest(http.MethodPost, url, nil, authClient, chatID)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return api.decodeResponse(resp, nil)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
 // isTokenExpired checks if the API response indicates an expired token.
 func (api *APIClient) isTokenExpired(response *http.Response) bool {
 	if response.StatusCode == http.StatusUnauthorized {
@@ -358,11 +472,16 @@ func (api *APIClient) isTokenExpired(response *http.Response) bool {
 	return false
 }
 
-// CompleteOrder completes the order and returns the order details.
-func (api *APIClient) CompleteOrder(authClient *auth.AuthClient, chatID int64) (*CompleteOrderResponse, error) {
+// CompleteOrder completes the order with the given checkout details and returns the order details.
+func (api *APIClient) CompleteOrder(authClient *auth.AuthClient, chatID int64, checkout CheckoutData) (*CompleteOrderResponse, error) {
 	url := api.BaseURL + "/orders"
 
-	resp, err := api.makeAPIRequest("POST", url, nil, authClient, chatID)
+	jsonData, err := json.Marshal(checkout)
+	if err != nil {
+		return nil, &Error{Err: err, Message: "Failed to json encode"}
+	}
+
+	resp, err := api.makeAPIRequest("POST", url, bytes.NewBuffer(jsonData), authClient, chatID)
 	if err != nil {
 		return nil, err
 	}
@@ -462,17 +581,240 @@ func (api *APIClient) UpdateField(chatID int64, authClient *auth.AuthClient, fie
 
 }
 
-// GetOrderHistory retrieves the order history and returns the order details.
-func (api *APIClient) GetOrderHistory(authClient *auth.AuthClient, chatID int64) (*OrderHistoryResponse, error) {
-	url := api.BaseURL + "/orders"
-	resp, err := api.makeAPIRequest("", url, nil, authClient, chatID)
+// GetOrderHistory retrieves one page of the order history matching the given filters.
+// It also reports whether there is a next page.
+func (api *APIClient) GetOrderHistory(query OrderHistoryQuery, authClient *auth.AuthClient, chatID int64) (*OrderHistoryResponse, bool, error) {
+	params := url.Values{}
+	if query.Page > 0 {
+		params.Set("page", strconv.Itoa(query.Page))
+	}
+	if query.PerPage > 0 {
+		params.Set("per_page", strconv.Itoa(query.PerPage))
+	}
+	if query.Status != "" {
+		params.Set("status", query.Status)
+	}
+	if !query.From.IsZero() {
+		params.Set("date_from", query.From.Format("2006-01-02"))
+	}
+	if !query.To.IsZero() {
+		params.Set("date_to", query.To.Format("2006-01-02"))
+	}
+	if query.Search != "" {
+		params.Set("search", query.Search)
+	}
+	if query.NewestFirst {
+		params.Set("sort", "desc")
+	}
+
+	urlStr := api.BaseURL + "/orders"
+	if len(params) > 0 {
+		urlStr += "?" + params.Encode()
+	}
+	resp, err := api.makeAPIRequest("", urlStr, nil, authClient, chatID)
 
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	var orderHistoryResponse OrderHistoryResponse
 	if err := api.decodeResponse(resp, &orderHistoryResponse); err != nil {
+		return nil, false, err
+	}
+	next := orderHistoryResponse.Links["next"]
+
+	return &orderHistoryResponse, next != nil, nil
+}
+
+// GetOrder retrieves a single order by its ID.
+func (api *APIClient) GetOrder(orderID int, authClient *auth.AuthClient, chatID int64) (*OrderResponse, error) {
+	url := fmt.Sprintf("%s/orders/%d", api.BaseURL, orderID)
+	resp, err := api.makeAPIRequest("", url, nil, authClient, chatID)
+
+	if err != nil {
+		return nil, err
+	}
+	var orderResponse OrderResponse
+	if err := api.decodeResponse(resp, &orderResponse); err != nil {
 		return nil, err
 	}
-	return &orderHistoryResponse, nil
+	return &orderResponse, nil
+}
+
+// CancelOrder cancels the order with the given ID and returns the updated order.
+func (api *APIClient) CancelOrder(orderID int, authClient *auth.AuthClient, chatID int64) (*OrderResponse, error) {
+	url := fmt.Sprintf("%s/orders/%d/cancel", api.BaseURL, orderID)
+	resp, err := api.makeAPIRequest(http.MethodPost, url, nil, authClient, chatID)
+
+	if err != nil {
+		return nil, err
+	}
+	var orderResponse OrderResponse
+	if err := api.decodeResponse(resp, &orderResponse); err != nil {
+		return nil, err
+	}
+	return &orderResponse, nil
+}
+
+// HasEndpoint reports whether the backend has a route for the collection at path, such as "/client/addresses".
+// Optional features are probed with it once: a collection answers 404 only when the route does not exist,
+// while a 404 for a single resource just means that the resource is gone.
+func (api *APIClient) HasEndpoint(path string, authClient *auth.AuthClient, chatID int64) (bool, error) {
+	resp, err := api.makeAPIRequest(http.MethodGet, api.BaseURL+path, nil, authClient, chatID)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	// The route exists even if it does not accept GET requests
+	case resp.StatusCode < 400 || resp.StatusCode == http.StatusMethodNotAllowed:
+		return true, nil
+	default:
+		return false, &Error{Err: fmt.Errorf("status %d", resp.StatusCode), Message: "Failed to probe " + path}
+	}
+}
+
+// GetAddresses retrieves the saved delivery addresses of the user.
+func (api *APIClient) GetAddresses(authClient *auth.AuthClient, chatID int64) ([]SavedAddress, error) {
+	url := api.BaseURL + "/client/addresses"
+	resp, err := api.makeAPIRequest("", url, nil, authClient, chatID)
+
+	if err != nil {
+		return nil, err
+	}
+	var addressesResponse AddressesResponse
+	if err := api.decodeResponse(resp, &addressesResponse); err != nil {
+		return nil, err
+	}
+	return addressesResponse.Data, nil
+}
+
+// SaveAddress creates a new saved address, or updates the existing one when the address has an ID.
+func (api *APIClient) SaveAddress(address SavedAddress, authClient *auth.AuthClient, chatID int64) (*SavedAddress, error) {
+	method := http.MethodPost
+	url := api.BaseURL + "/client/addresses"
+	if address.ID != 0 {
+		method = http.MethodPut
+		url = fmt.Sprintf("%s/client/addresses/%d", api.BaseURL, address.ID)
+	}
+
+	jsonData, err := json.Marshal(address)
+	if err != nil {
+		return nil, &Error{Err: err, Message: "Failed to json encode"}
+	}
+	resp, err := api.makeAPIRequest(method, url, bytes.NewBuffer(jsonData), authClient, chatID)
+	if err != nil {
+		return nil, err
+	}
+	var addressResponse AddressResponse
+	if err := api.decodeResponse(resp, &addressResponse); err != nil {
+		return nil, err
+	}
+	return &addressResponse.Data, nil
+}
+
+// DeleteAddress removes a saved address.
+func (api *APIClient) DeleteAddress(addressID int, authClient *auth.AuthClient, chatID int64) error {
+	url := fmt.Sprintf("%s/client/addresses/%d", api.BaseURL, addressID)
+
+	resp, err := api.makeAPIRequest(http.MethodDelete, url, nil, authClient, chatID)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return api.decodeResponse(resp, nil)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// SetDefaultAddress marks a saved address as the default delivery address.
+func (api *APIClient) SetDefaultAddress(addressID int, authClient *auth.AuthClient, chatID int64) error {
+	url := fmt.Sprintf("%s/client/addresses/%d/default", api.BaseURL, addressID)
+
+	resp, err := api.makeAPIRequest(http.MethodPost, url, nil, authClient, chatID)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return api.decodeResponse(resp, nil)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// ValidatePromoCode asks the backend whether the promo code can be applied to the cart of the user and returns its rules.
+// A code that cannot be applied is reported as an error with the reason returned by the API.
+func (api *APIClient) ValidatePromoCode(code string, authClient *auth.AuthClient, chatID int64) (*PromoCode, error) {
+	url := fmt.Sprintf("%s/promo-codes/validate", api.BaseURL)
+
+	jsonData, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		return nil, &Error{Err: err, Message: "Failed to json encode"}
+	}
+	resp, err := api.makeAPIRequest(http.MethodPost, url, bytes.NewBuffer(jsonData), authClient, chatID)
+	if err != nil {
+		return nil, err
+	}
+	var promoResponse PromoCodeResponse
+	if err := api.decodeResponse(resp, &promoResponse); err != nil {
+		return nil, err
+	}
+	return &promoResponse.Data, nil
+}
+
+// GetFavorites retrieves the products the user has added to their favorites.
+func (api *APIClient) GetFavorites(authClient *auth.AuthClient, chatID int64) ([]Product, error) {
+	url := api.BaseURL + "/client/favorites"
+	resp, err := api.makeAPIRequest("", url, nil, authClient, chatID)
+
+	if err != nil {
+		return nil, err
+	}
+	var favoritesResponse FavoritesResponse
+	if err := api.decodeResponse(resp, &favoritesResponse); err != nil {
+		return nil, err
+	}
+	return favoritesResponse.Data, nil
+}
+
+// AddFavorite adds a product to the favorites of the user.
+func (api *APIClient) AddFavorite(productID int, authClient *auth.AuthClient, chatID int64) error {
+	url := api.BaseURL + "/client/favorites"
+
+	jsonData, err := json.Marshal(map[string]int{"product_id": productID})
+	if err != nil {
+		return &Error{Err: err, Message: "Failed to json encode"}
+	}
+	resp, err := api.makeAPIRequest(http.MethodPost, url, bytes.NewBuffer(jsonData), authClient, chatID)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return api.decodeResponse(resp, nil)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// RemoveFavorite removes a product from the favorites of the user.
+func (api *APIClient) RemoveFavorite(productID int, authClient *auth.AuthClient, chatID int64) error {
+	url := fmt.Sprintf("%s/client/favorites/%d", api.BaseURL, productID)
+
+	resp, err := api.makeAPIRequest(http.MethodDelete, url, nil, authClient, chatID)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return api.decodeResponse(resp, nil)
+	}
+	resp.Body.Close()
+
+	return nil
 }
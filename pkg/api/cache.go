@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"my-telegram-bot/pkg/auth"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultCatalogCacheTTL is how long a catalog response is used without asking the backend again
+const DefaultCatalogCacheTTL = time.Minute
+
+// maxCatalogCacheEntries bounds the number of catalog responses kept, the oldest being dropped first
+const maxCatalogCacheEntries = 500
+
+// catalogCache keeps the bodies of the catalog responses by URL, so the page, the search and the filters are all part of the key.
+// Only the catalog itself is cached: the cart quantities of each user are added on top of it after every read.
+type catalogCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*catalogCacheEntry
+}
+
+// catalogCacheEntry is a cached response with the validators to revalidate it once it is stale
+type catalogCacheEntry struct {
+	body         []byte
+	etag         string
+	lastModified string
+	storedAt     time.Time
+}
+
+func newCatalogCache(ttl time.Duration) *catalogCache {
+	return &catalogCache{ttl: ttl, entries: make(map[string]*catalogCacheEntry)}
+}
+
+// get returns the entry cached for the URL and whether it is still fresh.
+func (c *catalogCache) get(url string) (*catalogCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	if !ok {
+		return nil, false
+	}
+	return entry, time.Since(entry.storedAt) < c.ttl
+}
+
+// put caches a response, making room by dropping the oldest entry when the cache is full.
+func (c *catalogCache) put(url string, entry *catalogCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return
+	}
+	if _, ok := c.entries[url]; !ok && len(c.entries) >= maxCatalogCacheEntries {
+		var oldestURL string
+		var oldest time.Time
+		for cachedURL, cached := range c.entries {
+			if oldestURL == "" || cached.storedAt.Before(oldest) {
+				oldestURL, oldest = cachedURL, cached.storedAt
+			}
+		}
+		delete(c.entries, oldestURL)
+	}
+	c.entries[url] = entry
+}
+
+// touch marks an entry as fresh again after the backend confirmed it has not changed.
+func (c *catalogCache) touch(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[url]; ok {
+		entry.storedAt = time.Now()
+	}
+}
+
+// SetCatalogCacheTTL changes how long catalog responses are used without asking the backend again.
+// A zero TTL turns the cache off.
+func (api *APIClient) SetCatalogCacheTTL(ttl time.Duration) {
+	api.catalogCache.mu.Lock()
+	defer api.catalogCache.mu.Unlock()
+	api.catalogCache.ttl = ttl
+	if ttl <= 0 {
+		api.catalogCache.entries = make(map[string]*catalogCacheEntry)
+	}
+}
+
+// getCatalog fetches a catalog response into result through the cache.
+// A fresh response is used as is. A stale one is revalidated with If-None-Match or If-Modified-Since,
+// and kept when the backend answers 304 Not Modified.
+func (api *APIClient) getCatalog(url string, authClient *auth.AuthClient, chatID int64, result interface{}) error {
+	entry, fresh := api.catalogCache.get(url)
+	if fresh {
+		return decodeCachedBody(entry.body, result)
+	}
+
+	header := http.Header{}
+	if entry != nil {
+		if entry.etag != "" {
+			header.Set("If-None-Match", entry.etag)
+		}
+		if entry.lastModified != "" {
+			header.Set("If-Modified-Since", entry.lastModified)
+		}
+	}
+	resp, err := api.makeAPIRequestWithHeader("", url, nil, authClient, chatID, header)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		api.catalogCache.touch(url)
+		return decodeCachedBody(entry.body, result)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return api.decodeResponse(resp, result)
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &Error{Err: err, Message: "Failed to read response"}
+	}
+	if err := decodeCachedBody(body, result); err != nil {
+		return err
+	}
+	api.catalogCache.put(url, &catalogCacheEntry{
+		body:         body,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		storedAt:     time.Now(),
+	})
+	return nil
+}
+
+// decodeCachedBody decodes a response body. Every read decodes its own copy, so callers may change the result freely.
+func decodeCachedBody(body []byte, result interface{}) error {
+	if err := json.Unmarshal(body, result); err != nil {
+		return &Error{Err: err, Message: "Error decoding response"}
+	}
+	return nil
+}
@@ -1,9 +1,13 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 )
 
+// ErrNotFound is wrapped by the errors returned for responses with the 404 status code.
+var ErrNotFound = errors.New("not found")
+
 // Error wraps an error with additional context
 type Error struct {
 	Err     error
@@ -22,6 +26,11 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s: %v", e.Message, e.Err)
 }
 
+// Unwrap returns the underlying error so that errors.Is can inspect it.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
 // ValidationError method returns the error message for the ValidationError struct.
 func (ve *ValidationError) ValidationError() string {
 	return ve.Message
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"my-telegram-bot/pkg/geo"
 	"net/http"
 	"time"
 )
@@ -8,8 +9,9 @@ import (
 // APIClient is a struct that holds the base URL for the API and an HTTP client.
 
 type APIClient struct {
-	BaseURL string
-	client  *http.Client
+	BaseURL      string
+	client       *http.Client
+	catalogCache *catalogCache
 }
 
 // RegisterData holds the data required for user registration.
@@ -20,6 +22,8 @@ type RegisterData struct {
 	Address   string `json:"address"`
 	Email     string `json:"email"`
 	Phone     string `json:"phone"`
+	// AddressDetails is set when the address was resolved from a shared location
+	AddressDetails *geo.Address `json:"address_details,omitempty"`
 }
 
 // RegisterResponse holds the response data for user registration.
@@ -51,9 +55,23 @@ type OrderResponseItem struct {
 	ID         int         `json:"id"`
 	ClientID   int         `json:"client_id"`
 	CourierID  int         `json:"courier_id"`
+	Courier    *Courier    `json:"courier,omitempty"`
 	Status     string      `json:"status"`
 	TotalPrice float64     `json:"totalPrice"`
+	Discount   float64     `json:"discount"`
+	PromoCode  string      `json:"promo_code"`
+	Address    string      `json:"address"`
 	OrderItems []OrderItem `json:"orderItems"`
+	CreatedAt  string      `json:"created_at"`
+	UpdatedAt  string      `json:"updated_at"`
+}
+
+// Courier represents the courier assigned to deliver an order.
+type Courier struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Phone     string `json:"phone"`
 }
 
 // OrderItem represents an item in an order.
@@ -73,9 +91,39 @@ type CompleteOrderResponse struct {
 	Data OrderResponseItem `json:"data"`
 }
 
+// CheckoutData holds the details sent to the backend together with a new order.
+type CheckoutData struct {
+	Address                 string       `json:"address,omitempty"`
+	AddressDetails          *geo.Address `json:"address_details,omitempty"`
+	Phone                   string       `json:"phone,omitempty"`
+	DeliveryTime            string       `json:"delivery_time,omitempty"`
+	Comment                 string       `json:"comment,omitempty"`
+	PromoCode               string       `json:"promo_code,omitempty"`
+	TelegramPaymentChargeID string       `json:"telegram_payment_charge_id,omitempty"`
+	ProviderPaymentChargeID string       `json:"provider_payment_charge_id,omitempty"`
+}
+
+// OrderResponse holds the response data for a single order.
+type OrderResponse struct {
+	Data OrderResponseItem `json:"data"`
+}
+
 // OrderHistoryResponse represents the response data for the order history endpoint.
 type OrderHistoryResponse struct {
-	Data []OrderResponseItem `json:"data"`
+	Data  []OrderResponseItem    `json:"data"`
+	Links map[string]interface{} `json:"links"`
+}
+
+// OrderHistoryQuery holds the pagination and filter parameters for the order history endpoint.
+// Zero values are not sent to the API.
+type OrderHistoryQuery struct {
+	Page        int
+	PerPage     int
+	Status      string
+	From        time.Time
+	To          time.Time
+	Search      string
+	NewestFirst bool
 }
 
 // Product represents a single product in the system.
@@ -87,6 +135,49 @@ type Product struct {
 	Image       string  `json:"image"`
 	Weight      int     `json:"weight"`
 	InCart      int     `json:"in_cart"`
+	// Images and Related are only returned with a single product
+	Images  []string  `json:"images,omitempty"`
+	Related []Product `json:"related_products,omitempty"`
+}
+
+// ProductResponse holds a single product returned from the API.
+type ProductResponse struct {
+	Data Product `json:"data"`
+}
+
+// Sort orders of the product catalog
+const (
+	SortPriceAsc   = "price_asc"
+	SortPriceDesc  = "price_desc"
+	SortNewest     = "newest"
+	SortPopularity = "popularity"
+)
+
+// ProductQuery selects a page of the product catalog.
+// Zero values leave the corresponding filter out.
+type ProductQuery struct {
+	Page       int
+	PerPage    int
+	Search     string
+	CategoryID int
+	Sort       string
+	MinPrice   float64
+	MaxPrice   float64
+	MinWeight  int
+	MaxWeight  int
+	InStock    bool
+}
+
+// Category is a node of the product catalog. Top-level categories have no parent.
+type Category struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentID int    `json:"parent_id"`
+}
+
+// CategoriesResponse holds the categories returned from the API.
+type CategoriesResponse struct {
+	Data []Category `json:"data"`
 }
 
 // ProductsResponse encapsulates the list of products returned from the API.
@@ -123,3 +214,47 @@ type CartResponse struct {
 		Products []CartItem `json:"products"`
 	} `json:"data"`
 }
+
+// SavedAddress represents a labelled delivery address in the user's address book.
+type SavedAddress struct {
+	ID             int          `json:"id"`
+	Label          string       `json:"label"`
+	Address        string       `json:"address"`
+	AddressDetails *geo.Address `json:"address_details,omitempty"`
+	IsDefault      bool         `json:"is_default"`
+}
+
+// AddressesResponse encapsulates the list of saved addresses returned from the API.
+type AddressesResponse struct {
+	Data []SavedAddress `json:"data"`
+}
+
+// AddressResponse holds a single saved address returned from the API.
+type AddressResponse struct {
+	Data SavedAddress `json:"data"`
+}
+
+// Promo code discount types
+const (
+	PromoTypePercentage = "percentage"
+	PromoTypeFixed      = "fixed"
+)
+
+// PromoCode describes a discount that can be applied to the cart.
+type PromoCode struct {
+	Code           string     `json:"code"`
+	Type           string     `json:"type"`
+	Value          float64    `json:"value"`
+	MinOrderAmount float64    `json:"min_order_amount"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// PromoCodeResponse holds a promo code returned from the API.
+type PromoCodeResponse struct {
+	Data PromoCode `json:"data"`
+}
+
+// FavoritesResponse encapsulates the favorite products of the user returned from the API.
+type FavoritesResponse struct {
+	Data []Product `json:"data"`
+}
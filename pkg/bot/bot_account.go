@@ -44,14 +44,14 @@ func (b *Bot) handleEditingState(msg *tgbotapi.Message) {
 	editingField := b.getUserEditingState(msg.Chat.ID)
 
 	if editingField == "image" {
-		if msg.Photo == nil {
+		if !hasImage(msg) {
 			b.handleAccountUpdateFailure(msg.Chat.ID, "Invalid input. Please upload a valid profile image.", nil)
 			return
 		}
 
 		imageData, err := b.downloadImageForEditing(msg)
 		if err != nil {
-			b.handleAccountUpdateFailure(msg.Chat.ID, fmt.Sprintf("Failed to download the image: %v", err), nil)
+			b.handleAccountUpdateFailure(msg.Chat.ID, fmt.Sprintf("Failed to process the image: %v", err), nil)
 			return
 		}
 
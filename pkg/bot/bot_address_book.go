@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"fmt"
+	"my-telegram-bot/pkg/api"
+	"my-telegram-bot/pkg/auth"
+	"my-telegram-bot/pkg/store"
+	"strconv"
+	"sync"
+)
+
+// addressBookBucket is the store bucket holding the address books kept by the bot itself
+const addressBookBucket = "addresses"
+
+// AddressBook manages the saved delivery addresses of the users.
+type AddressBook interface {
+	List(chatID int64) ([]api.SavedAddress, error)
+	// Save creates the address when it has no ID and updates it otherwise
+	Save(chatID int64, address api.SavedAddress) (*api.SavedAddress, error)
+	Delete(chatID int64, addressID int) error
+	SetDefault(chatID int64, addressID int) error
+}
+
+// apiAddressBook keeps the addresses on the backend.
+type apiAddressBook struct {
+	apiClient *api.APIClient
+	auth      *auth.AuthClient
+}
+
+func (a *apiAddressBook) List(chatID int64) ([]api.SavedAddress, error) {
+	return a.apiClient.GetAddresses(a.auth, chatID)
+}
+
+func (a *apiAddressBook) Save(chatID int64, address api.SavedAddress) (*api.SavedAddress, error) {
+	return a.apiClient.SaveAddress(address, a.auth, chatID)
+}
+
+func (a *apiAddressBook) Delete(chatID int64, addressID int) error {
+	return a.apiClient.DeleteAddress(addressID, a.auth, chatID)
+}
+
+func (a *apiAddressBook) SetDefault(chatID int64, addressID int) error {
+	return a.apiClient.SetDefaultAddress(addressID, a.auth, chatID)
+}
+
+// localAddressBook keeps the addresses in the bot's own store.
+type localAddressBook struct {
+	store *store.FileStore
+	mu    sync.Mutex
+}
+
+func (l *localAddressBook) load(chatID int64) ([]api.SavedAddress, error) {
+	var addresses []api.SavedAddress
+	if _, err := l.store.Get(addressBookBucket, strconv.FormatInt(chatID, 10), &addresses); err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+func (l *localAddressBook) save(chatID int64, addresses []api.SavedAddress) error {
+	return l.store.Put(addressBookBucket, strconv.FormatInt(chatID, 10), addresses)
+}
+
+func (l *localAddressBook) List(chatID int64) ([]api.SavedAddress, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.load(chatID)
+}
+
+func (l *localAddressBook) Save(chatID int64, address api.SavedAddress) (*api.SavedAddress, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	addresses, err := l.load(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if address.ID == 0 {
+		for _, existing := range addresses {
+			if existing.ID > address.ID {
+				address.ID = existing.ID
+			}
+		}
+		address.ID++
+		// The first address becomes the default one
+		address.IsDefault = len(addresses) == 0
+		addresses = append(addresses, address)
+	} else {
+		found := false
+		for i, existing := range addresses {
+			if existing.ID == address.ID {
+				address.IsDefault = existing.IsDefault
+				addresses[i] = address
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("address %d: %w", address.ID, api.ErrNotFound)
+		}
+	}
+
+	if err := l.save(chatID, addresses); err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+func (l *localAddressBook) Delete(chatID int64, addressID int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	addresses, err := l.load(chatID)
+	if err != nil {
+		return err
+	}
+
+	var kept []api.SavedAddress
+	removedDefault := false
+	for _, address := range addresses {
+		if address.ID == addressID {
+			removedDefault = address.IsDefault
+			continue
+		}
+		kept = append(kept, address)
+	}
+	// Keep a default address as long as there is one left
+	if removedDefault && len(kept) > 0 {
+		kept[0].IsDefault = true
+	}
+	return l.save(chatID, kept)
+}
+
+func (l *localAddressBook) SetDefault(chatID int64, addressID int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	addresses, err := l.load(chatID)
+	if err != nil {
+		return err
+	}
+	for i := range addresses {
+		addresses[i].IsDefault = addresses[i].ID == addressID
+	}
+	return l.save(chatID, addresses)
+}
+
+// fallbackAddressBook uses the backend endpoints when the backend has an address book,
+// and the bot's own store otherwise.
+type fallbackAddressBook struct {
+	feature *optionalFeature[AddressBook]
+}
+
+// newAddressBook creates an address book backed by the API, falling back to the store.
+func newAddressBook(apiClient *api.APIClient, authClient *auth.AuthClient, fileStore *store.FileStore) AddressBook {
+	return &fallbackAddressBook{feature: newOptionalFeature[AddressBook](
+		"saved addresses", "/client/addresses", apiClient, authClient,
+		&apiAddressBook{apiClient: apiClient, auth: authClient},
+		&localAddressBook{store: fileStore},
+	)}
+}
+
+func (f *fallbackAddressBook) List(chatID int64) ([]api.SavedAddress, error) {
+	return f.feature.get(chatID).List(chatID)
+}
+
+func (f *fallbackAddressBook) Save(chatID int64, address api.SavedAddress) (*api.SavedAddress, error) {
+	return f.feature.get(chatID).Save(chatID, address)
+}
+
+func (f *fallbackAddressBook) Delete(chatID int64, addressID int) error {
+	return f.feature.get(chatID).Delete(chatID, addressID)
+}
+
+func (f *fallbackAddressBook) SetDefault(chatID int64, addressID int) error {
+	return f.feature.get(chatID).SetDefault(chatID, addressID)
+}
+
+// defaultAddress returns the default address from the list, if there is one.
+func defaultAddress(addresses []api.SavedAddress) (api.SavedAddress, bool) {
+	for _, address := range addresses {
+		if address.IsDefault {
+			return address, true
+		}
+	}
+	return api.SavedAddress{}, false
+}
+
+// findAddress returns the address with the given ID from the list.
+func findAddress(addresses []api.SavedAddress, addressID int) (api.SavedAddress, bool) {
+	for _, address := range addresses {
+		if address.ID == addressID {
+			return address, true
+		}
+	}
+	return api.SavedAddress{}, false
+}
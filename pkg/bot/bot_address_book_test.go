@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"my-telegram-bot/pkg/api"
+	"net/http"
+	"testing"
+)
+
+func TestAddressBookUsesBackendWhenSupported(t *testing.T) {
+	b, _, backend := newTestBot(t)
+	backend.handleJSON("GET /client/addresses", http.StatusOK, api.AddressesResponse{Data: []api.SavedAddress{
+		{ID: 5, Label: "Home", Address: "12 Baker Street", IsDefault: true},
+	}})
+
+	// A 404 for a single address means it is gone, not that the backend has no address book
+	if err := b.addressBook.Delete(testChatID, 9); err == nil {
+		t.Fatal("deleting a missing address succeeded")
+	}
+
+	addresses, err := b.addressBook.List(testChatID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(addresses) != 1 || addresses[0].ID != 5 {
+		t.Errorf("List = %+v, want the backend address", addresses)
+	}
+	if got := len(backend.received("DELETE /client/addresses/9")); got != 1 {
+		t.Errorf("backend got %d delete requests, want 1", got)
+	}
+	// The probe and the list itself
+	if got := len(backend.received("GET /client/addresses")); got != 2 {
+		t.Errorf("backend got %d list requests, want 2", got)
+	}
+}
+
+func TestAddressBookFallsBackToStore(t *testing.T) {
+	b, _, backend := newTestBot(t)
+
+	saved, err := b.addressBook.Save(testChatID, api.SavedAddress{Label: "Work", Address: "1 Market Square"})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if saved.ID != 1 || !saved.IsDefault {
+		t.Errorf("Save = %+v, want the first address to be the default one", saved)
+	}
+
+	addresses, err := b.addressBook.List(testChatID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(addresses) != 1 || addresses[0].Address != "1 Market Square" {
+		t.Errorf("List = %+v, want the stored address", addresses)
+	}
+	if got := len(backend.received("GET /client/addresses")); got != 1 {
+		t.Errorf("backend was probed %d times, want once", got)
+	}
+	if got := len(backend.received("POST /client/addresses")); got != 0 {
+		t.Errorf("backend got %d save requests, want none", got)
+	}
+}
+
+func TestAddressBookProbesAgainAfterError(t *testing.T) {
+	b, _, backend := newTestBot(t)
+	backend.handleJSON("GET /client/addresses", http.StatusBadGateway, map[string]string{"message": "Bad Gateway"})
+
+	if _, err := b.addressBook.List(testChatID); err == nil {
+		t.Fatal("List succeeded while the backend was down")
+	}
+
+	backend.handleJSON("GET /client/addresses", http.StatusOK, api.AddressesResponse{Data: []api.SavedAddress{
+		{ID: 3, Label: "Home", Address: "12 Baker Street"},
+	}})
+	addresses, err := b.addressBook.List(testChatID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(addresses) != 1 || addresses[0].ID != 3 {
+		t.Errorf("List = %+v, want the backend address once it is back", addresses)
+	}
+}
@@ -0,0 +1,220 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"my-telegram-bot/pkg/api"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// handleAddressBook shows the saved addresses of the user, in place of messageID when it is not zero.
+func (b *Bot) handleAddressBook(chatID int64, messageID int) {
+	addresses, err := b.addressBook.List(chatID)
+	if err != nil {
+		log.Printf("Error fetching addresses: %v", err)
+		b.replyWithMessage(chatID, "Error fetching your addresses. Please try again later.", nil)
+		return
+	}
+
+	text := formatAddressBook(addresses)
+	keyboard := buildAddressBookKeyboard(addresses)
+	b.showAddressBookMessage(chatID, messageID, text, keyboard)
+}
+
+// handleAddressBookAction processes the buttons of the address book.
+func (b *Bot) handleAddressBookAction(chatID int64, messageID int, data string) {
+	switch {
+	case data == "book_list":
+		b.handleAddressBook(chatID, messageID)
+	case data == "book_add":
+		b.initUserState(chatID, nil)
+		b.setPendingAddress(chatID, api.SavedAddress{})
+		b.setDataForState(chatID, setCurrentStep, "book_label")
+		b.replyWithMessage(chatID, "Please enter a label for the new address (e.g. Home, Office):", nil)
+	default:
+		parts := strings.Split(data, "_")
+		addressID, err := strconv.Atoi(parts[len(parts)-1])
+		if err != nil {
+			return
+		}
+		addresses, err := b.addressBook.List(chatID)
+		if err != nil {
+			b.replyWithMessage(chatID, "Error fetching your addresses. Please try again later.", nil)
+			return
+		}
+		address, ok := findAddress(addresses, addressID)
+		if !ok {
+			b.replyWithMessage(chatID, "This address no longer exists.", nil)
+			b.handleAddressBook(chatID, messageID)
+			return
+		}
+		b.handleSavedAddressAction(chatID, messageID, strings.Join(parts[:len(parts)-1], "_"), address)
+	}
+}
+
+// handleSavedAddressAction applies an address book action to a single saved address.
+func (b *Bot) handleSavedAddressAction(chatID int64, messageID int, action string, address api.SavedAddress) {
+	switch action {
+	case "book_show":
+		b.showAddressBookMessage(chatID, messageID, formatSavedAddress(address), buildSavedAddressKeyboard(address))
+	case "book_rename":
+		b.initUserState(chatID, nil)
+		b.setPendingAddress(chatID, address)
+		b.setDataForState(chatID, setCurrentStep, "book_label")
+		b.replyWithMessage(chatID, fmt.Sprintf("Please enter a new label for '%s':", address.Label), nil)
+	case "book_change":
+		b.initUserState(chatID, nil)
+		b.setPendingAddress(chatID, address)
+		b.setDataForState(chatID, setCurrentStep, "book_address")
+		b.replyWithMessage(chatID, fmt.Sprintf("Please share the new address for '%s' or send your current location:", address.Label), createLocationKeyboard())
+	case "book_default":
+		if err := b.addressBook.SetDefault(chatID, address.ID); err != nil {
+			log.Printf("Error setting default address: %v", err)
+			b.replyWithMessage(chatID, "Error updating your addresses. Please try again later.", nil)
+			return
+		}
+		// Keep the address of the account in sync with the default one
+		if _, err := b.apiClient.UpdateField(chatID, b.auth, "address", address.Address); err != nil {
+			log.Printf("Error updating account address: %v", err)
+		}
+		b.handleAddressBook(chatID, messageID)
+	case "book_delete":
+		if err := b.addressBook.Delete(chatID, address.ID); err != nil {
+			log.Printf("Error deleting address: %v", err)
+			b.replyWithMessage(chatID, "Error updating your addresses. Please try again later.", nil)
+			return
+		}
+		b.handleAddressBook(chatID, messageID)
+	}
+}
+
+// handleAddressBookLabel stores the label typed by the user and asks for the address of new entries.
+func (b *Bot) handleAddressBookLabel(msg *tgbotapi.Message) {
+	label := strings.TrimSpace(msg.Text)
+	if label == "" {
+		b.replyWithMessage(msg.Chat.ID, "Value cannot be empty. Please enter a valid value.", nil)
+		return
+	}
+
+	address := b.getPendingAddress(msg.Chat.ID)
+	address.Label = label
+	if address.ID != 0 {
+		b.saveAddressBookEntry(msg.Chat.ID, address)
+		return
+	}
+
+	b.setPendingAddress(msg.Chat.ID, address)
+	b.setDataForState(msg.Chat.ID, setCurrentStep, "book_address")
+	b.replyWithMessage(msg.Chat.ID, fmt.Sprintf("Please share the address for '%s' or send your current location:", label), createLocationKeyboard())
+}
+
+// handleAddressBookAddress stores the address typed or shared by the user.
+func (b *Bot) handleAddressBookAddress(msg *tgbotapi.Message) {
+	if msg.Location != nil {
+		b.handleSharedLocation(msg, "book_address")
+		return
+	}
+
+	address := b.getPendingAddress(msg.Chat.ID)
+	address.Address = strings.TrimSpace(msg.Text)
+	address.AddressDetails = nil
+	if address.Address == "" {
+		b.replyWithMessage(msg.Chat.ID, "Value cannot be empty. Please enter a valid value.", nil)
+		return
+	}
+
+	b.saveAddressBookEntry(msg.Chat.ID, address)
+}
+
+// saveAddressBookEntry saves the pending address and shows the updated address book.
+func (b *Bot) saveAddressBookEntry(chatID int64, address api.SavedAddress) {
+	b.DeleteUserState(chatID)
+
+	if _, err := b.addressBook.Save(chatID, address); err != nil {
+		log.Printf("Error saving address: %v", err)
+		b.replyWithMessage(chatID, "Error saving the address. Please try again later.", tgbotapi.NewRemoveKeyboard(false))
+		return
+	}
+
+	b.replyWithMessage(chatID, "Address saved ✅", tgbotapi.NewRemoveKeyboard(false))
+	b.handleAddressBook(chatID, 0)
+}
+
+// showAddressBookMessage edits the address book message, or sends a new one when messageID is zero.
+func (b *Bot) showAddressBookMessage(chatID int64, messageID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) {
+	if messageID != 0 {
+		if err := b.editMessageWithReplyMarkup(chatID, messageID, text, "HTML", keyboard); err != nil {
+			log.Printf("Error editing address book: %v", err)
+		}
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = keyboard
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending address book: %v", err)
+	}
+}
+
+// formatAddressBook builds the HTML text listing the saved addresses.
+func formatAddressBook(addresses []api.SavedAddress) string {
+	if len(addresses) == 0 {
+		return "<b>Your delivery addresses</b> 📍\n\nYou have no saved addresses yet."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Your delivery addresses</b> 📍\n")
+	for _, address := range addresses {
+		sb.WriteString("\n" + formatSavedAddress(address) + "\n")
+	}
+	return sb.String()
+}
+
+// formatSavedAddress builds the HTML text of a single saved address.
+func formatSavedAddress(address api.SavedAddress) string {
+	title := fmt.Sprintf("<b>%s</b>", html.EscapeString(address.Label))
+	if address.IsDefault {
+		title = "⭐ " + title + " (default)"
+	}
+	return title + "\n" + html.EscapeString(address.Address)
+}
+
+// buildAddressBookKeyboard makes an inline keyboard with a button per saved address and a button to add a new one.
+func buildAddressBookKeyboard(addresses []api.SavedAddress) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, address := range addresses {
+		label := address.Label
+		if address.IsDefault {
+			label = "⭐ " + label
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("book_show_%d", address.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("➕ Add address", "book_add")))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// buildSavedAddressKeyboard makes an inline keyboard with the actions available for a saved address.
+func buildSavedAddressKeyboard(address api.SavedAddress) tgbotapi.InlineKeyboardMarkup {
+	secondRow := tgbotapi.NewInlineKeyboardRow()
+	if !address.IsDefault {
+		secondRow = append(secondRow, tgbotapi.NewInlineKeyboardButtonData("⭐ Make default", fmt.Sprintf("book_default_%d", address.ID)))
+	}
+	secondRow = append(secondRow, tgbotapi.NewInlineKeyboardButtonData("🗑 Delete", fmt.Sprintf("book_delete_%d", address.ID)))
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Rename", fmt.Sprintf("book_rename_%d", address.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Change address", fmt.Sprintf("book_change_%d", address.ID)),
+		),
+		secondRow,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", "book_list")),
+	)
+}
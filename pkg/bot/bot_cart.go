@@ -2,7 +2,13 @@ package bot
 
 import (
 	"fmt"
+	"html"
 	"log"
+	"my-telegram-bot/pkg/api"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
@@ -10,8 +16,20 @@ import (
 const (
 	// Menu message
 	menuMessage = "Please choose an option:"
+	// maxCartQuantity is the largest quantity of a product that can be typed in
+	maxCartQuantity = 99
+	// cartSyncTTL is how long the cached cart is trusted before it is checked against the backend again
+	cartSyncTTL = 2 * time.Minute
 )
 
+// CartChange describes a product whose quantity on the backend differs from the cached one
+type CartChange struct {
+	ProductID int
+	Name      string
+	Before    int
+	After     int
+}
+
 // sendMenu sends a menu to a chat identified by chatID
 func (b *Bot) sendMenu(chatID int64) {
 	menu := createMenuKeyboard()
@@ -49,6 +67,7 @@ func (b *Bot) InitUserCart(chatID int64) error {
 
 	// Add this user's cart to the bot's cart map
 	b.cart[chatID] = userCart
+	b.cartSyncedAt[chatID] = time.Now()
 
 	return nil
 }
@@ -88,7 +107,7 @@ func (b *Bot) buildCartKeyboard(chatID int64, productID int) tgbotapi.InlineKeyb
 	// Build buttons
 	buttons := []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("➕", fmt.Sprintf("add_to_cart_%d", productID)),
-		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🛒 %d", b.cart[chatID][productID].Quantity), "cart"),
+		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🛒 %d", b.cart[chatID][productID].Quantity), fmt.Sprintf("set_quantity_%d", productID)),
 		tgbotapi.NewInlineKeyboardButtonData("➖", fmt.Sprintf("reduce_amount_in_cart_%d", productID)),
 	}
 
@@ -97,9 +116,16 @@ func (b *Bot) buildCartKeyboard(chatID int64, productID int) tgbotapi.InlineKeyb
 		buttons = append(buttons, removeButton)
 	}
 
+	favoriteLabel := "☆"
+	if b.isFavorite(chatID, productID) {
+		favoriteLabel = "⭐"
+	}
+	buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(favoriteLabel, fmt.Sprintf("favorite_%d", productID)))
+
 	// Build keyboard
 	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(buttons...),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("ℹ️ Details", fmt.Sprintf("details_%d", productID))),
 	)
 }
 
@@ -112,3 +138,287 @@ func (b *Bot) isMostRecentMessage(chatID int64, messageID int, productID int) bo
 	}
 	return cartItem.MessageID == messageID
 }
+
+// syncCartQuantities overwrites the cached quantities with the cart items returned by the backend
+// and refreshes the product cards whose quantity has changed. It returns the changed products.
+func (b *Bot) syncCartQuantities(chatID int64, cartItems []api.CartItem) []CartChange {
+	if _, ok := b.cart[chatID]; !ok {
+		b.cart[chatID] = make(map[int]BotCartItem)
+	}
+	b.cartSyncedAt[chatID] = time.Now()
+
+	backendQuantities := make(map[int]int)
+	names := make(map[int]string)
+	for _, item := range cartItems {
+		backendQuantities[item.ProductID] = item.Quantity
+		names[item.ProductID] = item.ProductName
+	}
+
+	// Products that are cached locally but are missing on the backend are no longer in the cart
+	for productID := range b.cart[chatID] {
+		if _, ok := backendQuantities[productID]; !ok {
+			backendQuantities[productID] = 0
+		}
+	}
+
+	var changes []CartChange
+	for productID, quantity := range backendQuantities {
+		cartItem := b.cart[chatID][productID]
+		if cartItem.Quantity == quantity {
+			continue
+		}
+		changes = append(changes, CartChange{
+			ProductID: productID,
+			Name:      names[productID],
+			Before:    cartItem.Quantity,
+			After:     quantity,
+		})
+		cartItem.Quantity = quantity
+		b.cart[chatID][productID] = cartItem
+		if cartItem.MessageID != 0 {
+			if err := b.editCartMessage(chatID, cartItem.MessageID, productID); err != nil {
+				log.Printf("Error refreshing product card: %v", err)
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ProductID < changes[j].ProductID })
+	return changes
+}
+
+// reconcileCart refreshes the cached cart from the backend and tells the user about the products that changed elsewhere.
+func (b *Bot) reconcileCart(chatID int64) {
+	cartItems, err := b.apiClient.GetCartItems(b.auth, true, chatID)
+	if err != nil {
+		log.Printf("Error reconciling cart: %v", err)
+		return
+	}
+
+	if changes := b.syncCartQuantities(chatID, cartItems); len(changes) > 0 {
+		b.replyWithMessage(chatID, formatCartChanges(changes), nil)
+	}
+}
+
+// reconcileCartIfStale reconciles the cached cart when it has not been checked against the backend for cartSyncTTL.
+func (b *Bot) reconcileCartIfStale(chatID int64) {
+	// A cart that is not cached yet is loaded fresh by InitUserCart
+	if _, ok := b.cart[chatID]; !ok {
+		return
+	}
+	if time.Since(b.cartSyncedAt[chatID]) < cartSyncTTL {
+		return
+	}
+	b.reconcileCart(chatID)
+}
+
+// cartCallbackPrefixes lists the callbacks that change the cart from the cached quantities
+var cartCallbackPrefixes = []string{"add_to_cart_", "reduce_amount_in_cart_", "remove_from_cart_", "set_quantity_", "cartline_"}
+
+// isCartCallback reports whether the callback data changes the cart.
+func isCartCallback(data string) bool {
+	for _, prefix := range cartCallbackPrefixes {
+		if strings.HasPrefix(data, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatCartChanges describes the products whose quantity was changed outside of this chat.
+func formatCartChanges(changes []CartChange) string {
+	var sb strings.Builder
+	sb.WriteString("🔄 Your cart was changed elsewhere:")
+	for _, change := range changes {
+		name := change.Name
+		if name == "" {
+			name = fmt.Sprintf("Product #%d", change.ProductID)
+		}
+		switch {
+		case change.After == 0:
+			sb.WriteString(fmt.Sprintf("\n• %s: removed (was %d)", name, change.Before))
+		case change.Before == 0:
+			sb.WriteString(fmt.Sprintf("\n• %s: added (%d)", name, change.After))
+		default:
+			sb.WriteString(fmt.Sprintf("\n• %s: %d → %d", name, change.Before, change.After))
+		}
+	}
+	return sb.String()
+}
+
+// handleSetQuantityRequest asks the user to type the quantity of a product.
+func (b *Bot) handleSetQuantityRequest(chatID int64, productID int) {
+	b.initUserState(chatID, nil)
+	b.setDataForState(chatID, setCurrentStep, "quantity")
+	b.setPendingProduct(chatID, productID)
+
+	b.replyWithMessage(chatID, fmt.Sprintf(
+		"You have %d of this product in your cart. Please enter the new quantity (0-%d, 0 removes it from the cart):",
+		b.cart[chatID][productID].Quantity, maxCartQuantity,
+	), nil)
+}
+
+// handleQuantityInput validates the quantity typed by the user and applies it to the cart.
+func (b *Bot) handleQuantityInput(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	quantity, err := strconv.Atoi(strings.TrimSpace(msg.Text))
+	if err != nil || quantity < 0 || quantity > maxCartQuantity {
+		b.replyWithMessage(chatID, fmt.Sprintf("Please enter a whole number from 0 to %d.", maxCartQuantity), nil)
+		return
+	}
+
+	productID := b.getPendingProduct(chatID)
+	previousQuantity := b.cart[chatID][productID].Quantity
+	b.DeleteUserState(chatID)
+
+	if err := b.setCartQuantity(chatID, productID, quantity); err != nil {
+		log.Printf("Error setting cart quantity: %v", err)
+		return
+	}
+	if quantity == 0 {
+		b.offerUndo(chatID, map[int]int{productID: previousQuantity}, "Product is removed")
+	} else {
+		b.replyWithMessage(chatID, fmt.Sprintf("Quantity updated to %d.", quantity), nil)
+	}
+}
+
+// setCartQuantity sets the absolute quantity of a product in a single backend call and refreshes its product card.
+func (b *Bot) setCartQuantity(chatID int64, productID int, quantity int) error {
+	var err error
+	if quantity == 0 {
+		err = b.apiClient.RemoveProductFromCart(productID, b.auth, chatID, true)
+	} else {
+		err = b.apiClient.SetCartItemQuantity(productID, quantity, b.auth, chatID)
+	}
+	if err != nil {
+		b.replyWithMessage(chatID, "Error updating cart. Please try again.", nil)
+		b.reconcileCart(chatID)
+		return err
+	}
+
+	if _, ok := b.cart[chatID]; !ok {
+		b.cart[chatID] = make(map[int]BotCartItem)
+	}
+	cartItem := b.cart[chatID][productID]
+	cartItem.Quantity = quantity
+	b.cart[chatID][productID] = cartItem
+
+	if cartItem.MessageID != 0 {
+		if err := b.editCartMessage(chatID, cartItem.MessageID, productID); err != nil {
+			log.Printf("Error refreshing product card: %v", err)
+		}
+	}
+	return nil
+}
+
+// handleCartLineAction changes the quantity of a cart line from the cart summary and updates the summary in place.
+func (b *Bot) handleCartLineAction(chatID int64, messageID int, data string) {
+	parts := strings.Split(data, "_")
+	if len(parts) != 3 {
+		return
+	}
+	productID, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return
+	}
+
+	quantity := b.cart[chatID][productID].Quantity
+	switch parts[1] {
+	case "inc":
+		err = b.reduceOrIncreaseAmountInCart(chatID, productID, 1, false)
+	case "dec":
+		err = b.reduceOrIncreaseAmountInCart(chatID, productID, -1, true)
+	case "del":
+		err = b.reduceOrIncreaseAmountInCart(chatID, productID, 0, true)
+	default:
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating cart line: %v", err)
+		return
+	}
+
+	// Keep the product card in the catalog in line with the summary
+	if cartItem := b.cart[chatID][productID]; cartItem.MessageID != 0 {
+		if err := b.editCartMessage(chatID, cartItem.MessageID, productID); err != nil {
+			log.Printf("Error refreshing product card: %v", err)
+		}
+	}
+
+	b.renderCartSummary(chatID, messageID)
+	if parts[1] == "del" {
+		b.offerUndo(chatID, map[int]int{productID: quantity}, "Product is removed")
+	}
+}
+
+// renderCartSummary fetches the cart and redraws the cart summary message with the new lines and total.
+func (b *Bot) renderCartSummary(chatID int64, messageID int) {
+	cartItems, err := b.apiClient.GetCartItems(b.auth, true, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, "An error occurred while fetching your cart. Please try again.", nil)
+		return
+	}
+
+	promo, discount, reason := b.cartDiscount(chatID, cartItems)
+	text := formatCartSummary(cartItems, promo, discount, reason)
+	keyboard := buildCartSummaryKeyboard(cartItems, promo)
+	if err := b.editMessageWithReplyMarkup(chatID, messageID, text, "HTML", keyboard); err != nil {
+		log.Printf("Error editing cart summary: %v", err)
+	}
+}
+
+// formatCartSummary builds the HTML text of the cart summary with the total after the discount of the promo code.
+func formatCartSummary(cartItems []api.CartItem, promo *api.PromoCode, discount float64, reason string) string {
+	if len(cartItems) == 0 {
+		return "Your cart is empty."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Shopping Cart Items: \n")
+	totalCost := 0.0
+	for _, cartItem := range cartItems {
+		itemTotalPrice := float64(cartItem.Quantity) * cartItem.Price
+		totalCost += itemTotalPrice
+		sb.WriteString(fmt.Sprintf("<b>%s:</b> %d items | $%.2f \n", html.EscapeString(cartItem.ProductName), cartItem.Quantity, itemTotalPrice))
+	}
+	if discount > 0 {
+		sb.WriteString(fmt.Sprintf("\nSubtotal : $%.2f", totalCost))
+		sb.WriteString("\n" + html.EscapeString(formatDiscountLine(promo.Code, discount)))
+		totalCost -= discount
+	} else if reason != "" {
+		sb.WriteString("\n⚠️ " + html.EscapeString(reason))
+	}
+	sb.WriteString(fmt.Sprintf("\nTotal : $%.2f", totalCost))
+
+	return sb.String()
+}
+
+// buildCartSummaryKeyboard makes the inline keyboard of the cart summary with controls for every cart line.
+func buildCartSummaryKeyboard(cartItems []api.CartItem, promo *api.PromoCode) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, cartItem := range cartItems {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s: %d", cartItem.ProductName, cartItem.Quantity), "disabled"),
+		))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➖", fmt.Sprintf("cartline_dec_%d", cartItem.ProductID)),
+			tgbotapi.NewInlineKeyboardButtonData("➕", fmt.Sprintf("cartline_inc_%d", cartItem.ProductID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌", fmt.Sprintf("cartline_del_%d", cartItem.ProductID)),
+		))
+	}
+
+	catalogButton := tgbotapi.NewInlineKeyboardButtonData("🛒 Add more products", "modify_cart")
+	if len(cartItems) == 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(catalogButton))
+		return tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+
+	promoButton := tgbotapi.NewInlineKeyboardButtonData("🏷 Apply promo code", "promo_apply")
+	if promo != nil {
+		promoButton = tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✖️ Remove promo code %s", promo.Code), "promo_remove")
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(catalogButton, tgbotapi.NewInlineKeyboardButtonData("🛍 Complete Order", "complete_order")),
+		tgbotapi.NewInlineKeyboardRow(promoButton, tgbotapi.NewInlineKeyboardButtonData("🗑 Clear cart", "clear_cart")),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,184 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"my-telegram-bot/pkg/api"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// handleCategories shows the subcategories of a category as an inline keyboard, editing messageID when it is set.
+// A category without subcategories, or a catalog without categories, goes straight to the product list.
+func (b *Bot) handleCategories(chatID int64, messageID int, categoryID int) {
+	categories, err := b.apiClient.GetCategories(b.auth, chatID)
+	if err != nil {
+		log.Printf("Error fetching categories: %v", err)
+	} else {
+		b.categories = categories
+	}
+
+	children := childCategories(b.categories, categoryID)
+	if len(children) == 0 {
+		b.handleMakeOrder(chatID, api.ProductQuery{Page: 1, CategoryID: categoryID})
+		return
+	}
+
+	text := fmt.Sprintf("%s\n\nChoose a category:", categoryBreadcrumbs(b.categories, categoryID))
+	keyboard := buildCategoriesKeyboard(b.categories, categoryID, children)
+	if messageID != 0 {
+		if err := b.editMessageWithReplyMarkup(chatID, messageID, text, "", keyboard); err != nil {
+			log.Printf("Error showing categories: %v", err)
+		}
+		return
+	}
+	b.sendTextMessageWithReplyMarkup(chatID, text, keyboard)
+}
+
+// childCategories returns the direct subcategories of a category, or the top-level categories for 0.
+func childCategories(categories []api.Category, parentID int) []api.Category {
+	var children []api.Category
+	for _, category := range categories {
+		if category.ParentID == parentID && category.ID != parentID {
+			children = append(children, category)
+		}
+	}
+	return children
+}
+
+// findCategory returns the category with the given ID, or nil if there is none.
+func findCategory(categories []api.Category, categoryID int) *api.Category {
+	for i := range categories {
+		if categories[i].ID == categoryID {
+			return &categories[i]
+		}
+	}
+	return nil
+}
+
+// categoryBreadcrumbs returns the path from the top of the catalog to a category, like "Catalog › Dairy › Cheese".
+func categoryBreadcrumbs(categories []api.Category, categoryID int) string {
+	var path []string
+	// Bound the walk by the number of categories in case the tree has a cycle
+	for i := 0; categoryID != 0 && i <= len(categories); i++ {
+		category := findCategory(categories, categoryID)
+		if category == nil {
+			break
+		}
+		path = append([]string{category.Name}, path...)
+		categoryID = category.ParentID
+	}
+	return strings.Join(append([]string{"Catalog"}, path...), " › ")
+}
+
+// buildCategoriesKeyboard makes the keyboard of a category: its subcategories, a button listing all its products
+// and a button going back to its parent.
+func buildCategoriesKeyboard(categories []api.Category, categoryID int, children []api.Category) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, child := range children {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(child.Name, fmt.Sprintf("cat_%d", child.ID)))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🛍 Show all products", fmt.Sprintf("browse_%d", categoryID)),
+	))
+	if category := findCategory(categories, categoryID); category != nil {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", fmt.Sprintf("cat_%d", category.ParentID)),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// maxSavedQueries bounds the product queries kept for the catalog buttons of a chat
+const maxSavedQueries = 50
+
+// querySet keeps the product queries behind the catalog buttons of a chat under short IDs.
+// A search can be longer than the 64 bytes Telegram allows for callback data, so the buttons only carry the ID.
+type querySet struct {
+	queries map[int]api.ProductQuery
+	order   []int
+	nextID  int
+}
+
+// saveQuery keeps the query for the catalog buttons of the chat and returns its ID.
+// The page is not part of the saved query, the paging buttons carry it themselves.
+func (b *Bot) saveQuery(chatID int64, query api.ProductQuery) int {
+	query.Page, query.PerPage = 0, 0
+	set := b.savedQueries[chatID]
+	if set == nil {
+		set = &querySet{queries: make(map[int]api.ProductQuery)}
+		b.savedQueries[chatID] = set
+	}
+	for _, id := range set.order {
+		if set.queries[id] == query {
+			return id
+		}
+	}
+
+	set.nextID++
+	set.queries[set.nextID] = query
+	set.order = append(set.order, set.nextID)
+	if len(set.order) > maxSavedQueries {
+		delete(set.queries, set.order[0])
+		set.order = set.order[1:]
+	}
+	return set.nextID
+}
+
+// savedQuery returns the query saved under the ID from a callback.
+// It reports false when the query was forgotten, for instance after a restart or once newer queries pushed it out.
+func (b *Bot) savedQuery(chatID int64, id string) (api.ProductQuery, bool) {
+	queryID, err := strconv.Atoi(id)
+	if err != nil {
+		return api.ProductQuery{}, false
+	}
+	if set := b.savedQueries[chatID]; set != nil {
+		query, ok := set.queries[queryID]
+		return query, ok
+	}
+	return api.ProductQuery{}, false
+}
+
+// replyQueryExpired tells the user that the buttons they pressed belong to a list the bot no longer remembers.
+func (b *Bot) replyQueryExpired(chatID int64) {
+	b.replyWithMessage(chatID, "This list has expired. Please search again or open the catalog.", nil)
+}
+
+// parseRange reads a range like "5-20", "5-" or "-20". "any" or an empty range clears both bounds.
+func parseRange(text string) (min, max float64, err error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.NewReplacer("–", "-", "—", "-", ",", ".", " ", "", "$", "").Replace(text)
+	if text == "" || text == "any" || text == "-" {
+		return 0, 0, nil
+	}
+
+	from, to, found := strings.Cut(text, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("range %q has no dash", text)
+	}
+	if from != "" {
+		if min, err = strconv.ParseFloat(from, 64); err != nil || min < 0 {
+			return 0, 0, fmt.Errorf("invalid lower bound %q", from)
+		}
+	}
+	if to != "" {
+		if max, err = strconv.ParseFloat(to, 64); err != nil || max < 0 {
+			return 0, 0, fmt.Errorf("invalid upper bound %q", to)
+		}
+	}
+	if max > 0 && min > max {
+		return 0, 0, fmt.Errorf("lower bound %v is above upper bound %v", min, max)
+	}
+	return min, max, nil
+}
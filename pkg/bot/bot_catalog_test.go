@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"fmt"
+	"my-telegram-bot/pkg/api"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+func TestSavedQueries(t *testing.T) {
+	b, _, _ := newTestBot(t)
+	long := api.ProductQuery{
+		Page:     3,
+		Search:   strings.Repeat("organic whole grain sourdough ", 4),
+		Sort:     api.SortPriceDesc,
+		MinPrice: 2.5,
+		MaxPrice: 40,
+		InStock:  true,
+	}
+	longID := b.saveQuery(testChatID, long)
+	otherID := b.saveQuery(testChatID, api.ProductQuery{CategoryID: 7})
+
+	want := long
+	want.Page = 0
+	tests := []struct {
+		name   string
+		id     string
+		want   api.ProductQuery
+		wantOK bool
+	}{
+		{name: "long search", id: fmt.Sprint(longID), want: want, wantOK: true},
+		{name: "category", id: fmt.Sprint(otherID), want: api.ProductQuery{CategoryID: 7}, wantOK: true},
+		{name: "unknown ID", id: "999"},
+		{name: "no ID", id: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := b.savedQuery(testChatID, tt.id); got != tt.want || ok != tt.wantOK {
+				t.Errorf("savedQuery(%q) = %+v, %v; want %+v, %v", tt.id, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if id := b.saveQuery(testChatID, api.ProductQuery{Page: 5, CategoryID: 7}); id != otherID {
+		t.Errorf("saving the same query on another page gave ID %d, want %d", id, otherID)
+	}
+	if got, ok := b.savedQuery(testChatID+1, fmt.Sprint(longID)); ok {
+		t.Errorf("another chat got the query %+v", got)
+	}
+}
+
+func TestSavedQueriesForgetTheOldest(t *testing.T) {
+	b, _, _ := newTestBot(t)
+	first := b.saveQuery(testChatID, api.ProductQuery{CategoryID: 1})
+	var last int
+	for i := 0; i < maxSavedQueries; i++ {
+		last = b.saveQuery(testChatID, api.ProductQuery{CategoryID: i + 2})
+	}
+
+	if got, ok := b.savedQuery(testChatID, fmt.Sprint(first)); ok {
+		t.Errorf("the oldest query is still saved: %+v", got)
+	}
+	if got, _ := b.savedQuery(testChatID, fmt.Sprint(last)); got.CategoryID != maxSavedQueries+1 {
+		t.Errorf("the newest query = %+v, want category %d", got, maxSavedQueries+1)
+	}
+	if got := len(b.savedQueries[testChatID].queries); got != maxSavedQueries {
+		t.Errorf("%d queries saved, want %d", got, maxSavedQueries)
+	}
+}
+
+func TestPaginationKeyboardKeepsLongSearch(t *testing.T) {
+	b, _, _ := newTestBot(t)
+	query := api.ProductQuery{Page: 2, Search: strings.Repeat("ванильное мороженое ", 5), CategoryID: 12, Sort: api.SortNewest}
+	keyboard := createPaginationKeyboard(query, b.saveQuery(testChatID, query), true)
+
+	var next string
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if data := *button.CallbackData; len(data) > 64 {
+				t.Errorf("button %q has %d bytes of callback data, Telegram allows 64", button.Text, len(data))
+			} else if strings.HasPrefix(data, "next_page_") {
+				next = data
+			}
+		}
+	}
+
+	parts := strings.SplitN(next, "_", 4)
+	if len(parts) != 4 {
+		t.Fatalf("next page data %q has no query ID", next)
+	}
+	if got, _ := b.savedQuery(testChatID, parts[3]); got.Search != query.Search || got.CategoryID != 12 || got.Sort != api.SortNewest {
+		t.Errorf("next page query = %+v, want the full search of %+v", got, query)
+	}
+
+	b.handleSortAction(testChatID, 42, strings.Replace(next, "next_page_2", "sort_menu", 1))
+	if got := b.catalogQueries[testChatID]; got.Search != query.Search {
+		t.Errorf("sort menu edits the search %q, want %q", got.Search, query.Search)
+	}
+}
+
+func TestExpiredQueryAsksToSearchAgain(t *testing.T) {
+	expired := fmt.Sprint(maxSavedQueries + 1)
+	for _, data := range []string{
+		"next_page_2_" + expired,
+		"previous_page_2_" + expired,
+		"next_page_2",
+		"sort_menu_" + expired,
+		"filter_menu_" + expired,
+		"search_for_" + expired,
+	} {
+		t.Run(data, func(t *testing.T) {
+			b, telegram, backend := newTestBot(t)
+			b.saveQuery(testChatID, api.ProductQuery{Search: "cheese"})
+
+			b.handleCallbackQuery(&tgbotapi.CallbackQuery{
+				ID:      "1",
+				Data:    data,
+				Message: &tgbotapi.Message{MessageID: 42, Chat: &tgbotapi.Chat{ID: testChatID}},
+			})
+
+			if !containsText(telegram.texts(), "This list has expired") {
+				t.Errorf("replies %q do not say the list has expired", telegram.texts())
+			}
+			if got := len(backend.received("GET /products")); got != 0 {
+				t.Errorf("backend received %d product requests, want none", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"my-telegram-bot/pkg/api"
+	"my-telegram-bot/pkg/geo"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// CheckoutState stores the order details collected by the checkout wizard
+type CheckoutState struct {
+	MessageID      int
+	Address        string
+	AddressDetails *geo.Address
+	Phone          string
+	DeliveryTime   string
+	Comment        string
+	// DeliveryOptions are the delivery times offered by the last delivery time keyboard
+	DeliveryOptions []string
+}
+
+// asapDelivery is the delivery time chosen when the user does not pick a window.
+const asapDelivery = "As soon as possible"
+
+// deliveryWindows are the delivery windows of a day, as the hours they start and end at.
+var deliveryWindows = [][2]int{{9, 12}, {12, 15}, {15, 18}, {18, 21}}
+
+// deliveryTimeOptions lists the delivery times the user can choose from at checkout at the given time:
+// as soon as possible, the windows of today that have not started yet and every window of tomorrow.
+func deliveryTimeOptions(now time.Time) []string {
+	options := []string{asapDelivery}
+	for _, window := range deliveryWindows {
+		if now.Hour() < window[0] {
+			options = append(options, fmt.Sprintf("Today %02d:00-%02d:00", window[0], window[1]))
+		}
+	}
+	for _, window := range deliveryWindows {
+		options = append(options, fmt.Sprintf("Tomorrow %02d:00-%02d:00", window[0], window[1]))
+	}
+	return options
+}
+
+// checkoutFieldPrompts maps the editable checkout fields to the prompt asking for their new value.
+var checkoutFieldPrompts = map[string]string{
+	"address": "Please enter the delivery address:",
+	"phone":   "Please enter the contact phone number:",
+	"comment": "Please enter a comment for the courier, or send '-' to remove it:",
+}
+
+// checkoutData converts the collected details into the data sent to the backend with the order.
+func (s *CheckoutState) checkoutData() api.CheckoutData {
+	return api.CheckoutData{
+		Address:        s.Address,
+		AddressDetails: s.AddressDetails,
+		Phone:          s.Phone,
+		DeliveryTime:   s.DeliveryTime,
+		Comment:        s.Comment,
+	}
+}
+
+// startCheckout opens the checkout wizard prefilled with the address and phone from the user's account.
+// The default address of the address book takes precedence over the account address.
+func (b *Bot) startCheckout(chatID int64) {
+	state := &CheckoutState{DeliveryTime: asapDelivery}
+
+	accountInfo, err := b.apiClient.GetAccountInfo(b.auth, chatID)
+	if err != nil {
+		log.Printf("Error fetching account info for checkout: %v", err)
+	} else {
+		state.Address = accountInfo.Data.Address
+		state.Phone = accountInfo.Data.Phone
+	}
+
+	addresses, err := b.addressBook.List(chatID)
+	if err != nil {
+		log.Printf("Error fetching addresses for checkout: %v", err)
+	} else if address, ok := defaultAddress(addresses); ok {
+		state.Address = address.Address
+		state.AddressDetails = address.AddressDetails
+	}
+
+	b.checkouts[chatID] = state
+	b.renderCheckout(chatID, state)
+}
+
+// renderCheckout shows the order review with the collected details, either in a new message or in place of the previous one.
+func (b *Bot) renderCheckout(chatID int64, state *CheckoutState) {
+	cartItems, err := b.apiClient.GetCartItems(b.auth, true, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, "An error occurred while fetching your cart. Please try again.", nil)
+		return
+	}
+	if len(cartItems) == 0 {
+		delete(b.checkouts, chatID)
+		b.replyWithMessage(chatID, "Your cart is empty. Please add at least one product to the cart before placing an order.", nil)
+		return
+	}
+
+	promo, discount, _ := b.cartDiscount(chatID, cartItems)
+	text := formatCheckoutReview(cartItems, state, promo, discount)
+	keyboard := buildCheckoutKeyboard()
+
+	if state.MessageID != 0 {
+		if err := b.editMessageWithReplyMarkup(chatID, state.MessageID, text, "HTML", keyboard); err != nil {
+			log.Printf("Error editing checkout review: %v", err)
+		}
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = keyboard
+	sentMsg, err := b.bot.Send(msg)
+	if err != nil {
+		log.Printf("Error sending checkout review: %v", err)
+		return
+	}
+	state.MessageID = sentMsg.MessageID
+}
+
+// handleCheckoutAction processes the buttons of the checkout wizard.
+func (b *Bot) handleCheckoutAction(chatID int64, messageID int, data string) {
+	state, ok := b.checkouts[chatID]
+	if !ok {
+		b.replyWithMessage(chatID, "This checkout has expired. Please start it again.", nil)
+		return
+	}
+	state.MessageID = messageID
+
+	switch {
+	case data == "checkout_address":
+		addresses, err := b.addressBook.List(chatID)
+		if err != nil {
+			log.Printf("Error fetching addresses for checkout: %v", err)
+		}
+		if len(addresses) == 0 {
+			b.promptCheckoutField(chatID, data)
+			return
+		}
+		edit := tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, buildSavedAddressesKeyboard(addresses, state.Address))
+		if _, err := b.bot.Send(edit); err != nil {
+			log.Printf("Error showing saved addresses: %v", err)
+		}
+	case data == "checkout_address_new":
+		b.promptCheckoutField(chatID, "checkout_address")
+	case strings.HasPrefix(data, "checkout_addr_"):
+		addressID, _ := strconv.Atoi(strings.TrimPrefix(data, "checkout_addr_"))
+		addresses, err := b.addressBook.List(chatID)
+		if err != nil {
+			log.Printf("Error fetching addresses for checkout: %v", err)
+		}
+		if address, ok := findAddress(addresses, addressID); ok {
+			state.Address = address.Address
+			state.AddressDetails = address.AddressDetails
+		}
+		b.renderCheckout(chatID, state)
+	case data == "checkout_phone" || data == "checkout_comment":
+		b.promptCheckoutField(chatID, data)
+	case data == "checkout_time":
+		state.DeliveryOptions = deliveryTimeOptions(time.Now())
+		edit := tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, buildDeliveryTimeKeyboard(state.DeliveryOptions, state.DeliveryTime))
+		if _, err := b.bot.Send(edit); err != nil {
+			log.Printf("Error showing delivery times: %v", err)
+		}
+	case strings.HasPrefix(data, "checkout_time_"):
+		index, err := strconv.Atoi(strings.TrimPrefix(data, "checkout_time_"))
+		if err == nil && index >= 0 && index < len(state.DeliveryOptions) {
+			state.DeliveryTime = state.DeliveryOptions[index]
+		}
+		b.renderCheckout(chatID, state)
+	case data == "checkout_back":
+		b.renderCheckout(chatID, state)
+	case data == "checkout_confirm":
+		b.confirmCheckout(chatID, state)
+	case data == "checkout_cancel":
+		delete(b.checkouts, chatID)
+		b.closeCheckoutMessage(chatID, messageID, "Checkout cancelled. Your cart is kept for later.")
+		b.sendMenu(chatID)
+	}
+}
+
+// promptCheckoutField asks the user to type a new value for a checkout field.
+func (b *Bot) promptCheckoutField(chatID int64, step string) {
+	b.initUserState(chatID, nil)
+	b.setDataForState(chatID, setCurrentStep, step)
+	b.replyWithMessage(chatID, checkoutFieldPrompts[strings.TrimPrefix(step, "checkout_")], nil)
+}
+
+// handleCheckoutInput stores the value typed by the user for the checkout field being edited.
+func (b *Bot) handleCheckoutInput(msg *tgbotapi.Message, step string) {
+	chatID := msg.Chat.ID
+	state, ok := b.checkouts[chatID]
+	if !ok {
+		b.DeleteUserState(chatID)
+		b.replyWithMessage(chatID, "This checkout has expired. Please start it again.", nil)
+		return
+	}
+
+	value := strings.TrimSpace(msg.Text)
+	switch step {
+	case "checkout_address":
+		if value == "" {
+			b.replyWithMessage(chatID, "Value cannot be empty. Please enter a valid value.", nil)
+			return
+		}
+		// Keep the current step so the user can enter another address right away
+		details, reason := b.deliveryZoneRejection(value, nil)
+		if reason != "" {
+			b.replyWithMessage(chatID, reason+"\nPlease enter another delivery address:", nil)
+			return
+		}
+		state.Address = value
+		state.AddressDetails = details
+	case "checkout_phone":
+		if value == "" {
+			b.replyWithMessage(chatID, "Value cannot be empty. Please enter a valid value.", nil)
+			return
+		}
+		state.Phone = value
+	case "checkout_comment":
+		if value == "-" {
+			value = ""
+		}
+		state.Comment = value
+	}
+	b.DeleteUserState(chatID)
+
+	// The old review is above the user's input, so show the updated one in a new message
+	state.MessageID = 0
+	b.renderCheckout(chatID, state)
+}
+
+// confirmCheckout places the order, or sends the invoice first when payments are enabled.
+func (b *Bot) confirmCheckout(chatID int64, state *CheckoutState) {
+	if state.Address == "" || state.Phone == "" {
+		b.replyWithMessage(chatID, "Please fill in the delivery address and the contact phone before confirming the order.", nil)
+		return
+	}
+	// The address may come from the account or an older address book entry, which were never checked
+	details, reason := b.deliveryZoneRejection(state.Address, state.AddressDetails)
+	if reason != "" {
+		b.replyWithMessage(chatID, reason+"\nPlease check the delivery address or choose another one.", nil)
+		return
+	}
+	state.AddressDetails = details
+
+	b.closeCheckoutMessage(chatID, state.MessageID, "Order details confirmed ✅")
+
+	// With payments enabled the order is placed once Telegram confirms the payment,
+	// so the collected details are kept until then
+	if b.payments != nil {
+		b.sendInvoice(chatID)
+		return
+	}
+
+	delete(b.checkouts, chatID)
+	if err := b.placeOrder(chatID, state.checkoutData()); err != nil {
+		b.replyWithMessage(chatID, fmt.Sprintf("Error completing the order: %v Please try again later.", err), nil)
+		b.sendMenu(chatID)
+	}
+}
+
+// closeCheckoutMessage replaces the checkout review with a final text and removes its keyboard.
+func (b *Bot) closeCheckoutMessage(chatID int64, messageID int, text string) {
+	if _, err := b.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, text)); err != nil {
+		log.Printf("Error closing checkout review: %v", err)
+	}
+}
+
+// formatCheckoutReview builds the HTML text of the order review, with the discount of the promo code when there is one.
+func formatCheckoutReview(cartItems []api.CartItem, state *CheckoutState, promo *api.PromoCode, discount float64) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>Review your order</b> 🧾\n\n<b>Items:</b>\n")
+	totalCost := 0.0
+	for _, cartItem := range cartItems {
+		itemTotalPrice := float64(cartItem.Quantity) * cartItem.Price
+		totalCost += itemTotalPrice
+		sb.WriteString(fmt.Sprintf("%s: %d x $%.2f = $%.2f\n", html.EscapeString(cartItem.ProductName), cartItem.Quantity, cartItem.Price, itemTotalPrice))
+	}
+	if discount > 0 {
+		sb.WriteString(fmt.Sprintf("<b>Subtotal:</b> $%.2f\n", totalCost))
+		sb.WriteString(html.EscapeString(formatDiscountLine(promo.Code, discount)) + "\n")
+		totalCost -= discount
+	}
+	sb.WriteString(fmt.Sprintf("<b>Total:</b> $%.2f\n\n", totalCost))
+
+	sb.WriteString(fmt.Sprintf("📍 <b>Delivery address:</b> %s\n", checkoutValue(state.Address)))
+	sb.WriteString(fmt.Sprintf("📞 <b>Contact phone:</b> %s\n", checkoutValue(state.Phone)))
+	sb.WriteString(fmt.Sprintf("🕒 <b>Delivery time:</b> %s\n", checkoutValue(state.DeliveryTime)))
+	sb.WriteString(fmt.Sprintf("💬 <b>Comment:</b> %s\n", checkoutValue(state.Comment)))
+	sb.WriteString("\nUse the buttons below to change any detail, then confirm the order.")
+
+	return sb.String()
+}
+
+// checkoutValue escapes a checkout detail for display and marks empty values.
+func checkoutValue(value string) string {
+	if value == "" {
+		return "—"
+	}
+	return html.EscapeString(value)
+}
+
+// buildCheckoutKeyboard makes the inline keyboard of the order review.
+func buildCheckoutKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📍 Address", "checkout_address"),
+			tgbotapi.NewInlineKeyboardButtonData("📞 Phone", "checkout_phone"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🕒 Delivery time", "checkout_time"),
+			tgbotapi.NewInlineKeyboardButtonData("💬 Comment", "checkout_comment"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Confirm", "checkout_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Cancel", "checkout_cancel"),
+		),
+	)
+}
+
+// buildDeliveryTimeKeyboard makes an inline keyboard with the delivery times, marking the selected one.
+func buildDeliveryTimeKeyboard(options []string, selected string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, option := range options {
+		label := option
+		if option == selected {
+			label = "✅ " + option
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("checkout_time_%d", i)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", "checkout_back")))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// buildSavedAddressesKeyboard makes an inline keyboard to pick the delivery address from the address book.
+func buildSavedAddressesKeyboard(addresses []api.SavedAddress, selected string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, address := range addresses {
+		label := fmt.Sprintf("%s: %s", address.Label, address.Address)
+		if address.Address == selected {
+			label = "✅ " + label
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("checkout_addr_%d", address.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("✏️ Enter another address", "checkout_address_new")))
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", "checkout_back")))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeliveryTimeOptions(t *testing.T) {
+	tomorrow := []string{"Tomorrow 09:00-12:00", "Tomorrow 12:00-15:00", "Tomorrow 15:00-18:00", "Tomorrow 18:00-21:00"}
+	tests := []struct {
+		name  string
+		hour  int
+		today []string
+	}{
+		{"early morning", 7, []string{"Today 09:00-12:00", "Today 12:00-15:00", "Today 15:00-18:00", "Today 18:00-21:00"}},
+		{"morning window started", 9, []string{"Today 12:00-15:00", "Today 15:00-18:00", "Today 18:00-21:00"}},
+		{"afternoon", 14, []string{"Today 15:00-18:00", "Today 18:00-21:00"}},
+		{"late evening", 22, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 3, 5, tt.hour, 30, 0, 0, time.Local)
+			want := append(append([]string{asapDelivery}, tt.today...), tomorrow...)
+			if got := deliveryTimeOptions(now); strings.Join(got, "|") != strings.Join(want, "|") {
+				t.Errorf("deliveryTimeOptions(%s) = %q, want %q", now.Format("15:04"), got, want)
+			}
+		})
+	}
+}
+
+func TestDeliveryTimeChosenFromShownOptions(t *testing.T) {
+	b, _, _ := newTestBot(t)
+	state := &CheckoutState{MessageID: 42, DeliveryTime: asapDelivery}
+	b.checkouts[testChatID] = state
+
+	b.handleCheckoutAction(testChatID, 42, "checkout_time")
+	if len(state.DeliveryOptions) == 0 || state.DeliveryOptions[0] != asapDelivery {
+		t.Fatalf("offered delivery times %q", state.DeliveryOptions)
+	}
+
+	// The options stay the ones shown even if the time moves on before the user picks one
+	state.DeliveryOptions = []string{asapDelivery, "Tomorrow 09:00-12:00"}
+	b.handleCheckoutAction(testChatID, 42, "checkout_time_1")
+	if state.DeliveryTime != "Tomorrow 09:00-12:00" {
+		t.Errorf("delivery time = %q, want the option shown on the button", state.DeliveryTime)
+	}
+
+	b.handleCheckoutAction(testChatID, 42, "checkout_time_7")
+	if state.DeliveryTime != "Tomorrow 09:00-12:00" {
+		t.Errorf("an unknown option changed the delivery time to %q", state.DeliveryTime)
+	}
+}
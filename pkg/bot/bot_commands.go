@@ -5,10 +5,8 @@ import (
 	"html"
 	"log"
 	"my-telegram-bot/pkg/api"
-	"net/url"
 	"strconv"
 	"strings"
-	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
@@ -20,21 +18,30 @@ func (b *Bot) handleStart(chatID int64) {
 }
 
 // handleAddress processes the address shared by the user.
-// If the user sends a location, it uses the latitude and longitude of the location. Otherwise, it uses the text message as the address.
+// If the user sends a location, it is resolved into an address the user confirms first. Otherwise, it uses the text message as the address.
 func (b *Bot) handleAddress(msg *tgbotapi.Message) {
 	// Check if the user sent a location or a text message
 	if msg.Location != nil {
-		// Use the latitude and longitude to get an address
-		b.setDataForState(msg.Chat.ID, setAddress, fmt.Sprintf("Lat: %f, Long: %f", msg.Location.Latitude, msg.Location.Longitude))
-	} else {
-		// Use the provided address
-		b.setDataForState(msg.Chat.ID, setAddress, msg.Text)
+		b.handleSharedLocation(msg, "address")
+		return
+	}
+
+	// Use the provided address, keeping the current step if the shop doesn't deliver there
+	details, reason := b.deliveryZoneRejection(msg.Text, nil)
+	if reason != "" {
+		b.replyWithMessage(msg.Chat.ID, reason+"\nPlease enter another address or share your location:", createLocationKeyboard())
+		return
 	}
+	b.setDataForState(msg.Chat.ID, setAddress, msg.Text)
+	b.setAddressDetails(msg.Chat.ID, details)
+	b.askForEmail(msg.Chat.ID)
+}
 
-	// Ask for the email
-	b.setDataForState(msg.Chat.ID, setCurrentStep, "email")
+// askForEmail moves the registration on to the email step.
+func (b *Bot) askForEmail(chatID int64) {
+	b.setDataForState(chatID, setCurrentStep, "email")
 
-	b.replyWithMessage(msg.Chat.ID, "Please enter your email address:", tgbotapi.ReplyKeyboardRemove{
+	b.replyWithMessage(chatID, "Please enter your email address:", tgbotapi.ReplyKeyboardRemove{
 		RemoveKeyboard: true,
 		Selective:      false,
 	})
@@ -45,12 +52,12 @@ func (b *Bot) handleEmail(msg *tgbotapi.Message) {
 	b.setDataForState(msg.Chat.ID, setEmail, msg.Text)
 	// Ask for the image
 	b.setDataForState(msg.Chat.ID, setCurrentStep, "image")
-	b.replyWithMessage(msg.Chat.ID, "Please upload your profile image (jpeg, png, jpg, gif, svg with max size 2048KB) or send 'SKIP' to skip this step:", nil)
+	b.replyWithMessage(msg.Chat.ID, "Please upload your profile image as a photo or a jpeg, png or gif file, or send 'SKIP' to skip this step:", nil)
 }
 
 // handleImage processes the profile image shared by the user.
 func (b *Bot) handleImage(msg *tgbotapi.Message) {
-	if msg.Photo != nil {
+	if hasImage(msg) {
 		b.handlePhotoImage(msg)
 	} else if strings.ToLower(msg.Text) == "skip" {
 		b.handleSkipImage(msg)
@@ -61,11 +68,9 @@ func (b *Bot) handleImage(msg *tgbotapi.Message) {
 	b.handleRegistration(msg)
 }
 func (b *Bot) handlePhotoImage(msg *tgbotapi.Message) {
-	photoSize := (*msg.Photo)[len(*msg.Photo)-1]
-	fileID := photoSize.FileID
-	imageData, err := b.downloadImage(fileID)
+	imageData, err := b.downloadProfileImage(msg)
 	if err != nil {
-		b.replyWithMessage(msg.Chat.ID, fmt.Sprintf("Failed to download the image: %v. Please try again.", err), nil)
+		b.replyWithMessage(msg.Chat.ID, fmt.Sprintf("Failed to process the image: %v. Please try again.", err), nil)
 		return
 	}
 	b.setDataForImageState(msg.Chat.ID, setImage, imageData)
@@ -75,7 +80,7 @@ func (b *Bot) handleSkipImage(msg *tgbotapi.Message) {
 }
 
 func (b *Bot) handleInvalidImageInput(msg *tgbotapi.Message) {
-	b.replyWithMessage(msg.Chat.ID, "Invalid input. Please upload your profile image (jpeg, png, jpg, gif, svg with max size 2048KB) or send 'SKIP'", nil)
+	b.replyWithMessage(msg.Chat.ID, "Invalid input. Please upload your profile image as a photo or a jpeg, png or gif file, or send 'SKIP'", nil)
 }
 
 func (b *Bot) handleRegistration(msg *tgbotapi.Message) {
@@ -105,55 +110,49 @@ func (b *Bot) handleSharedContact(msg *tgbotapi.Message) {
 
 // handleMakeOrder displays the list of products for ordering.
 // It also sends an inline keyboard with paging and search button.
-func (b *Bot) handleMakeOrder(chatID int64, page int, search string) {
-	// Call the API to retrieve the list of products
-	products, hasNextPage, err := b.apiClient.GetProducts(perPage, page, b.auth, chatID, search)
+func (b *Bot) handleMakeOrder(chatID int64, query api.ProductQuery) {
+	query.PerPage = perPage
+	// Retrieve the list of products, searches going through the search index
+	products, hasNextPage, err := b.findProducts(chatID, query)
 	if err != nil {
 		b.replyWithMessage(chatID, fmt.Sprintf("An error occurred while fetching products: %v. Please try again later.", err), nil)
 		return
 	}
 
 	if len(products) == 0 {
+		if query.Search != "" && query.Page <= 1 {
+			b.handleNoSearchResults(chatID, query)
+			return
+		}
 		b.replyWithMessage(chatID, "No more products available.", nil)
 		return
 	}
 	b.InitUserCart(chatID)
+	b.reconcileCartIfStale(chatID)
 	for _, product := range products {
-		if product.Image != "" {
-			b.sendImage(chatID, product.Image, "product")
-		}
-
-		productInfo := fmt.Sprintf("<b>Name:</b> %s\n<b>Price:</b> $%.2f\n<b>Weight:</b> %d g\n<b>Description:</b> %s",
-			html.EscapeString(product.Name), product.Price, product.Weight, html.EscapeString(product.Description))
-
-		// Create inline keyboard buttons for adding and removing the product from the cart
-		// Use buildCartKeyboard to generate the inline keyboard
-		inlineKeyboard := b.buildCartKeyboard(chatID, product.ID)
-		// Send product information text with the inline keyboard
-		textMsg := tgbotapi.NewMessage(chatID, productInfo)
-		textMsg.ParseMode = "HTML"
-		textMsg.ReplyMarkup = inlineKeyboard
-		sentMsg, err := b.bot.Send(textMsg)
-		if err != nil {
+		if err := b.sendProductCard(chatID, product); err != nil {
 			b.replyWithMessage(chatID, fmt.Sprintf("An error occurred while sending products: %v. Please try again later.", err), nil)
 			return
 		}
-		// Update the MessageID in the cart
-		cartItem := b.cart[chatID][product.ID]
-		cartItem.MessageID = sentMsg.MessageID
-		b.cart[chatID][product.ID] = cartItem
 	}
 
 	// Send the inline keyboard with paging and the search button
-	var menu tgbotapi.InlineKeyboardMarkup
+	menu := createPaginationKeyboard(query, b.saveQuery(chatID, query), hasNextPage)
 	var menuText string
-	if search != "" {
-		menu = createPaginationKeyboard(page, hasNextPage, search)
-		menuText = fmt.Sprintf("Results for '%s'. Use the buttons below to navigate between pages:", search)
+	if query.Search != "" {
+		menuText = fmt.Sprintf("Results for '%s'. Use the buttons below to navigate between pages:", query.Search)
+		if suggestion := b.searchSuggestion(query.Search); suggestion != "" {
+			menuText = fmt.Sprintf("Results for '%s', did you mean '%s'? Use the buttons below to navigate between pages:", query.Search, suggestion)
+		}
 	} else {
-		menu = createPaginationKeyboard(page, hasNextPage, "")
 		menuText = "Use the buttons below to navigate between pages or search for a specific product:"
 	}
+	if summary := formatProductQuery(query); summary != "" {
+		menuText = fmt.Sprintf("%s\n\n%s", summary, menuText)
+	}
+	if query.CategoryID > 0 {
+		menuText = fmt.Sprintf("%s\n\n%s", categoryBreadcrumbs(b.categories, query.CategoryID), menuText)
+	}
 	b.sendTextMessageWithReplyMarkup(chatID, menuText, menu)
 }
 
@@ -171,7 +170,73 @@ func (b *Bot) handleSearch(chatID int64, page int, searchQuery string) {
 	}
 	b.DeleteUserState(chatID)
 	// Call the refactored handleMakeOrder with the search query
-	b.handleMakeOrder(chatID, page, searchQuery)
+	b.handleMakeOrder(chatID, api.ProductQuery{Page: page, Search: searchQuery})
+}
+
+// sendProductCard sends the image and the details of a product with the inline keyboard to manage it in the cart.
+func (b *Bot) sendProductCard(chatID int64, product api.Product) error {
+	// Remember the product details for the actions started from its card
+	b.products.put(product)
+
+	if product.Image != "" {
+		b.sendImage(chatID, product.Image, "product")
+	}
+
+	productInfo := fmt.Sprintf("<b>Name:</b> %s\n<b>Price:</b> $%.2f\n<b>Weight:</b> %d g\n<b>Description:</b> %s",
+		html.EscapeString(product.Name), product.Price, product.Weight, html.EscapeString(product.Description))
+
+	// Create inline keyboard buttons for adding and removing the product from the cart
+	// Use buildCartKeyboard to generate the inline keyboard
+	inlineKeyboard := b.buildCartKeyboard(chatID, product.ID)
+	// Send product information text with the inline keyboard
+	textMsg := tgbotapi.NewMessage(chatID, productInfo)
+	textMsg.ParseMode = "HTML"
+	textMsg.ReplyMarkup = inlineKeyboard
+	sentMsg, err := b.bot.Send(textMsg)
+	if err != nil {
+		return err
+	}
+	// Update the MessageID in the cart
+	if _, ok := b.cart[chatID]; !ok {
+		b.cart[chatID] = make(map[int]BotCartItem)
+	}
+	cartItem := b.cart[chatID][product.ID]
+	cartItem.MessageID = sentMsg.MessageID
+	b.cart[chatID][product.ID] = cartItem
+	return nil
+}
+
+// maxRememberedProducts bounds the product details kept for the actions started from product cards.
+const maxRememberedProducts = 1000
+
+// productCache keeps the details of the products shown most recently, forgetting the oldest ones first.
+type productCache struct {
+	limit    int
+	products map[int]api.Product
+	order    []int
+}
+
+// newProductCache creates a product cache holding up to limit products.
+func newProductCache(limit int) *productCache {
+	return &productCache{limit: limit, products: make(map[int]api.Product)}
+}
+
+// put remembers the product, replacing the details known for it.
+func (c *productCache) put(product api.Product) {
+	if _, ok := c.products[product.ID]; !ok {
+		c.order = append(c.order, product.ID)
+		if len(c.order) > c.limit {
+			delete(c.products, c.order[0])
+			c.order = c.order[1:]
+		}
+	}
+	c.products[product.ID] = product
+}
+
+// get returns the details of the product if they are still remembered.
+func (c *productCache) get(productID int) (api.Product, bool) {
+	product, ok := c.products[productID]
+	return product, ok
 }
 
 // handleCallbackQuery handles the callback queries from the inline keyboard buttons.
@@ -180,6 +245,11 @@ func (b *Bot) handleCallbackQuery(callbackQuery *tgbotapi.CallbackQuery) {
 	chatID := callbackQuery.Message.Chat.ID
 	messageID := callbackQuery.Message.MessageID
 
+	// Check the cached cart before acting on the product cards built from it
+	if isCartCallback(data) {
+		b.reconcileCartIfStale(chatID)
+	}
+
 	switch {
 	case data == "disabled":
 		return
@@ -210,7 +280,7 @@ func (b *Bot) handleCallbackQuery(callbackQuery *tgbotapi.CallbackQuery) {
 	case data == "back":
 		b.sendMenu(chatID)
 	case data == "modify_cart":
-		b.handleMakeOrder(chatID, 1, "")
+		b.handleCategories(chatID, 0, 0)
 	case data == "complete_order":
 		b.handleCompleteOrder(chatID, false)
 	case data == "cart":
@@ -244,11 +314,79 @@ func (b *Bot) handleCallbackQuery(callbackQuery *tgbotapi.CallbackQuery) {
 			b.replyWithMessage(chatID, "🚨 Warning: 🚨 \nYou're trying to update the cart from an older message. Please scroll to the most recent message to make changes to your cart. 🛒", nil)
 			return
 		}
+		quantity := b.cart[chatID][productID].Quantity
 		err := b.reduceOrIncreaseAmountInCart(chatID, productID, 0, true)
 		if err == nil {
 			b.editCartMessage(chatID, messageID, productID)
-			b.replyWithMessage(chatID, "Product is removed", nil)
+			b.offerUndo(chatID, map[int]int{productID: quantity}, "Product is removed")
+		}
+	case strings.HasPrefix(data, "cat_"):
+		categoryID, _ := strconv.Atoi(strings.TrimPrefix(data, "cat_"))
+		b.handleCategories(chatID, callbackQuery.Message.MessageID, categoryID)
+	case strings.HasPrefix(data, "browse_"):
+		categoryID, _ := strconv.Atoi(strings.TrimPrefix(data, "browse_"))
+		b.handleMakeOrder(chatID, api.ProductQuery{Page: 1, CategoryID: categoryID})
+	case strings.HasPrefix(data, "search_for"):
+		query, ok := b.savedQuery(chatID, strings.TrimPrefix(data, "search_for_"))
+		if !ok {
+			b.replyQueryExpired(chatID)
+			return
+		}
+		query.Page = 1
+		b.handleMakeOrder(chatID, query)
+	case strings.HasPrefix(data, "sort_"):
+		b.handleSortAction(chatID, callbackQuery.Message.MessageID, data)
+	case strings.HasPrefix(data, "filter_"):
+		b.handleFilterAction(chatID, callbackQuery.Message.MessageID, data)
+	case strings.HasPrefix(data, "details_"):
+		productID, _ := strconv.Atoi(strings.TrimPrefix(data, "details_"))
+		b.handleProductDetails(chatID, productID)
+	case strings.HasPrefix(data, "favorite_"):
+		productID, _ := strconv.Atoi(strings.TrimPrefix(data, "favorite_"))
+		b.handleToggleFavorite(chatID, messageID, productID)
+	case data == "undo_removal":
+		b.handleUndoRemoval(chatID, messageID)
+	case strings.HasPrefix(data, "clear_cart"):
+		b.handleClearCartAction(chatID, messageID, data)
+	case strings.HasPrefix(data, "cartline_"):
+		b.handleCartLineAction(chatID, messageID, data)
+	case strings.HasPrefix(data, "set_quantity_"):
+		productID, _ := strconv.Atoi(strings.TrimPrefix(data, "set_quantity_"))
+		if !b.isMostRecentMessage(chatID, messageID, productID) {
+			b.replyWithMessage(chatID, "🚨 Warning: 🚨 \nYou're trying to update the cart from an older message. Please scroll to the most recent message to make changes to your cart. 🛒", nil)
+			return
 		}
+		b.handleSetQuantityRequest(chatID, productID)
+	case strings.HasPrefix(data, "order_details_"):
+		orderID, _ := strconv.Atoi(strings.TrimPrefix(data, "order_details_"))
+		b.handleOrderDetails(chatID, messageID, orderID)
+	case strings.HasPrefix(data, "cancel_order_"):
+		orderID, origin := parseCancelCallback(strings.TrimPrefix(data, "cancel_order_"))
+		b.handleCancelOrderRequest(chatID, messageID, orderID, origin)
+	case strings.HasPrefix(data, "confirm_cancel_order_"):
+		orderID, origin := parseCancelCallback(strings.TrimPrefix(data, "confirm_cancel_order_"))
+		b.handleConfirmCancelOrder(chatID, messageID, orderID, origin)
+	case strings.HasPrefix(data, "order_receipt_"):
+		orderID, _ := strconv.Atoi(strings.TrimPrefix(data, "order_receipt_"))
+		b.handleOrderReceipt(chatID, orderID)
+	case strings.HasPrefix(data, "reorder_"):
+		orderID, _ := strconv.Atoi(strings.TrimPrefix(data, "reorder_"))
+		b.handleReorder(chatID, orderID)
+	case strings.HasPrefix(data, "keep_order_"):
+		orderID, origin := parseCancelCallback(strings.TrimPrefix(data, "keep_order_"))
+		b.handleKeepOrder(chatID, messageID, orderID, origin)
+	case data == "orders_back":
+		b.showOrderHistoryPage(chatID, messageID)
+	case strings.HasPrefix(data, "orders_"):
+		b.handleOrderHistoryAction(chatID, messageID, data)
+	case strings.HasPrefix(data, "checkout_"):
+		b.handleCheckoutAction(chatID, messageID, data)
+	case strings.HasPrefix(data, "book_"):
+		b.handleAddressBookAction(chatID, messageID, data)
+	case strings.HasPrefix(data, "addr_"):
+		b.handleAddressConfirmationAction(chatID, messageID, data)
+	case strings.HasPrefix(data, "promo_"):
+		b.handlePromoAction(chatID, data)
 	default:
 		b.replyWithMessage(chatID, "Sorry, I didn't understand your action. Please try again.", nil)
 	}
@@ -265,7 +403,13 @@ func (b *Bot) handleCartAction(chatID int64) {
 	cartItems, err := b.apiClient.GetCartItems(b.auth, true, chatID)
 	if err != nil {
 		b.replyWithMessage(chatID, "An error occurred while fetching your cart. Please try again.", nil)
-	} else if len(cartItems) == 0 {
+		return
+	}
+	// The cart was just fetched, so bring the cached quantities in line with it
+	if changes := b.syncCartQuantities(chatID, cartItems); len(changes) > 0 {
+		b.replyWithMessage(chatID, formatCartChanges(changes), nil)
+	}
+	if len(cartItems) == 0 {
 		b.replyWithMessage(chatID, "Your cart is empty.", nil)
 		b.sendMenu(chatID)
 	} else {
@@ -274,46 +418,47 @@ func (b *Bot) handleCartAction(chatID int64) {
 }
 
 func (b *Bot) handlePreviousPage(data string, chatID int64) {
-	parts := strings.Split(data, "_")
+	parts := strings.SplitN(data, "_", 4)
 	page, _ := strconv.Atoi(parts[2])
-	page -= 1
-	search := ""
-	if len(parts) > 3 {
-		search, _ = url.QueryUnescape(parts[3])
+	if len(parts) < 4 {
+		b.replyQueryExpired(chatID)
+		return
 	}
-	b.handleMakeOrder(chatID, page, search)
+	query, ok := b.savedQuery(chatID, parts[3])
+	if !ok {
+		b.replyQueryExpired(chatID)
+		return
+	}
+	query.Page = page - 1
+	b.handleMakeOrder(chatID, query)
 }
 
 func (b *Bot) handleNextPage(data string, chatID int64) {
-	parts := strings.Split(data, "_")
+	parts := strings.SplitN(data, "_", 4)
 	page, _ := strconv.Atoi(parts[2])
-	page += 1
-	search := ""
-	if len(parts) > 3 {
-		search, _ = url.QueryUnescape(parts[3])
+	if len(parts) < 4 {
+		b.replyQueryExpired(chatID)
+		return
 	}
-	b.handleMakeOrder(chatID, page, search)
+	query, ok := b.savedQuery(chatID, parts[3])
+	if !ok {
+		b.replyQueryExpired(chatID)
+		return
+	}
+	query.Page = page + 1
+	b.handleMakeOrder(chatID, query)
 }
 
+// handleUserCart sends the cart summary as a single message that can be edited from its own buttons.
 func (b *Bot) handleUserCart(cartItems []api.CartItem, chatID int64) {
-	cartMessage := "Shopping Cart Items: \n"
-	totalCost := 0.0
-	for _, cartItem := range cartItems {
-		itemTotalPrice := float64(cartItem.Quantity) * cartItem.Price
-		totalCost += itemTotalPrice
-		cartMessage += fmt.Sprintf("<b>%s:</b> %d items | $%.2f \n", html.EscapeString(cartItem.ProductName), cartItem.Quantity, itemTotalPrice)
-	}
-	cartMessage += fmt.Sprintf("\nTotal : $%.2f", totalCost)
+	promo, discount, reason := b.cartDiscount(chatID, cartItems)
 
-	textMsg := tgbotapi.NewMessage(chatID, cartMessage)
-	textMsg.ParseMode = "HTML"
-	b.bot.Send(textMsg)
-
-	// Add 'Edit Cart' and 'Complete Order' buttons
-	editCartButton := tgbotapi.NewInlineKeyboardButtonData("🛒 Edit the Cart", "modify_cart")
-	completeOrderButton := tgbotapi.NewInlineKeyboardButtonData("🛍 Complete Order", "complete_order")
-	inlineKeyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(editCartButton, completeOrderButton))
-	b.sendTextMessageWithReplyMarkup(chatID, "Choose your action:", inlineKeyboard)
+	msg := tgbotapi.NewMessage(chatID, formatCartSummary(cartItems, promo, discount, reason))
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = buildCartSummaryKeyboard(cartItems, promo)
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending cart summary: %v", err)
+	}
 }
 
 func (b *Bot) sendTextMessageWithReplyMarkup(chatID int64, text string, replyMarkup interface{}) {
@@ -337,6 +482,8 @@ func (b *Bot) reduceOrIncreaseAmountInCart(chatID int64, productID int, amount i
 
 	if err != nil {
 		b.replyWithMessage(chatID, "Error updating cart. Please try again.", nil)
+		// The cached cart may be out of date, which is a common reason for the failure
+		b.reconcileCart(chatID)
 		return err
 	}
 
@@ -383,12 +530,13 @@ func (b *Bot) handleRegistrationSuccess(msg *tgbotapi.Message, registerData api.
 	greetingMessage := fmt.Sprintf("Hello, %s! Welcome to our bot. To get started, use the buttons we'll provide to make orders, manage your account, view your order history, and manage your cart.", registerData.FirstName)
 	b.replyWithMessage(msg.Chat.ID, greetingMessage, nil)
 	b.sendMenu(msg.Chat.ID)
+	b.openPendingDeepLink(msg.Chat.ID)
 
 	// Clear the state for this chat
 	b.DeleteUserState(msg.Chat.ID)
 }
 
-// handleCompleteOrder processes the user's request to complete an order.
+// handleCompleteOrder processes the user's request to complete an order by starting the checkout wizard.
 func (b *Bot) handleCompleteOrder(chatID int64, sendMenuOnFailing bool) {
 	if len(b.cart[chatID]) == 0 {
 		b.replyWithMessage(chatID, "Your cart is empty. Please add at least one product to the cart before placing an order.", nil)
@@ -397,21 +545,36 @@ func (b *Bot) handleCompleteOrder(chatID int64, sendMenuOnFailing bool) {
 		}
 		return
 	}
+	b.startCheckout(chatID)
+}
+
+// placeOrder sends the order with the checkout details to the backend, reports the result and resets the cart.
+// When the backend rejects the order, the caller tells the user, since what to say depends on whether it was paid.
+func (b *Bot) placeOrder(chatID int64, checkout api.CheckoutData) error {
+	if promo := b.appliedPromos[chatID]; promo != nil {
+		b.attachPromoCode(chatID, promo, &checkout)
+	}
+
 	// Call the CompleteOrder function of the APIClient to complete the order
-	orderResponse, err := b.apiClient.CompleteOrder(b.auth, chatID)
+	orderResponse, err := b.apiClient.CompleteOrder(b.auth, chatID, checkout)
 	if err != nil {
-		b.replyWithMessage(chatID, fmt.Sprintf("Error completing the order: %v Please try again later.", err), nil)
-		b.sendMenu(chatID)
-		return
+		return err
+	}
+	if orderResponse.Data.Address == "" {
+		// Older backends don't echo the delivery address, the receipt needs it
+		orderResponse.Data.Address = checkout.Address
 	}
 
 	// Constructing the response message with details from CompleteOrderResponse
 	responseMsg := fmt.Sprintf(
-		"Order Completed!\nOrder ID: %d\nStatus: %s\nTotal Price: %.2f\n",
+		"Order Completed!\nOrder ID: %d\nStatus: %s\n",
 		orderResponse.Data.ID,
 		orderResponse.Data.Status,
-		orderResponse.Data.TotalPrice,
 	)
+	if orderResponse.Data.Discount > 0 {
+		responseMsg += formatDiscountLine(checkout.PromoCode, orderResponse.Data.Discount) + "\n"
+	}
+	responseMsg += fmt.Sprintf("Total Price: %.2f\n", orderResponse.Data.TotalPrice)
 
 	for _, item := range orderResponse.Data.OrderItems {
 		responseMsg += fmt.Sprintf(
@@ -423,6 +586,7 @@ func (b *Bot) handleCompleteOrder(chatID int64, sendMenuOnFailing bool) {
 	}
 
 	b.replyWithMessage(chatID, responseMsg, nil)
+	b.sendReceipt(chatID, orderResponse.Data)
 
 	// Reset quantities to 0 and update display
 	for productID, cartItem := range b.cart[chatID] {
@@ -431,9 +595,11 @@ func (b *Bot) handleCompleteOrder(chatID int64, sendMenuOnFailing bool) {
 		b.editCartMessage(chatID, cartItem.MessageID, productID) // Update cart display
 	}
 
-	// Clear the cart entirely
+	// Clear the cart entirely, the promo code was used up by the order
 	delete(b.cart, chatID)
+	delete(b.appliedPromos, chatID)
 	b.sendMenu(chatID)
+	return nil
 }
 
 // handleMyAccount fetches and displays the user's account details and provides editing options.
@@ -469,6 +635,11 @@ func (b *Bot) handleMyAccount(chatID int64, accountInfoFromUpdate *api.AccountIn
 		b.sendMessageWithEditButton(chatID, fmt.Sprintf("*%s* ➤ `%s`", field.Name, field.Value), field.EditData)
 	}
 
+	addressBookButton := tgbotapi.NewInlineKeyboardButtonData("📍 Address book", "book_list")
+	b.sendTextMessageWithReplyMarkup(chatID, "Manage your saved delivery addresses:",
+		tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(addressBookButton)))
+
 	daysMessage := fmt.Sprintf("You are our favorite customer already for *%d* days! 🎉🥳", accountInfo.Data.DaysSinceCreation)
 	msg := tgbotapi.NewMessage(chatID, daysMessage)
 	msg.ParseMode = "Markdown"
@@ -484,64 +655,3 @@ func (b *Bot) sendMessageWithEditButton(chatID int64, msgText, editData string)
 	msg.ParseMode = "Markdown"
 	b.bot.Send(msg)
 }
-
-func (b *Bot) handleOrderHistory(chatID int64) {
-	orderHistory, err := b.apiClient.GetOrderHistory(b.auth, chatID)
-	if err != nil {
-		b.replyWithMessage(chatID, "Error fetching order history. Please try again later.", nil)
-		b.sendMenu(chatID)
-		return
-	}
-
-	if len(orderHistory.Data) == 0 {
-		b.replyWithMessage(chatID, "You have no orders yet. Start shopping to see your orders here! 🛍️", nil)
-		b.sendMenu(chatID)
-		return
-	}
-
-	reversedOrders := reverseOrderArray(orderHistory.Data)
-
-	for i, order := range reversedOrders {
-		orderNumber := len(reversedOrders) - i
-		orderStatus := strings.ToUpper(order.Status)
-		orderTotalPrice := fmt.Sprintf("%.2f💲", order.TotalPrice)
-
-		timestamp, err := time.Parse(time.RFC3339Nano, order.OrderItems[0].CreatedAt)
-		if err != nil {
-			fmt.Println("Error parsing timestamp:", err)
-			continue
-		}
-		orderCreatedAt := timestamp.Format("Monday, 02 January 2006")
-
-		var orderItemsStrings []string
-		for _, item := range order.OrderItems {
-			orderItemsStrings = append(orderItemsStrings, fmt.Sprintf("%d x %s", item.Quantity, item.ProductName))
-		}
-		orderItemsList := strings.Join(orderItemsStrings, "\n")
-
-		messageText := fmt.Sprintf(
-			"*Order #%d* 📦\n"+
-				"*Status:* `%s`\n"+
-				"*Total Price: *%s\n"+
-				"*Date:* %s\n"+
-				"*Items:*\n%s",
-			orderNumber, orderStatus, orderTotalPrice, orderCreatedAt, orderItemsList,
-		)
-		msg := tgbotapi.NewMessage(chatID, messageText)
-		msg.ParseMode = "Markdown"
-		b.bot.Send(msg)
-	}
-	b.sendMenu(chatID)
-}
-
-func reverseOrderArray(orders []api.OrderResponseItem) []api.OrderResponseItem {
-	// Create a new slice to hold the reversed order
-	reversed := make([]api.OrderResponseItem, len(orders))
-
-	// Iterate through the original slice in reverse order and copy each element to the new slice
-	for i, j := len(orders)-1, 0; i >= 0; i, j = i-1, j+1 {
-		reversed[j] = orders[i]
-	}
-
-	return reversed
-}
@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"my-telegram-bot/pkg/api"
+	"my-telegram-bot/pkg/pdf"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const (
+	// Number of orders requested per page while collecting the orders to export
+	exportPerPage = 50
+	// Upper bound of pages fetched for a single export
+	exportMaxPages = 100
+)
+
+// handleOrderExport sends the orders matching the current order history filters as CSV and PDF documents.
+func (b *Bot) handleOrderExport(chatID int64, view *OrderHistoryView) {
+	orders, err := b.collectOrdersForExport(chatID, view)
+	if err != nil {
+		b.replyWithMessage(chatID, "Error fetching order history. Please try again later.", nil)
+		return
+	}
+	if len(orders) == 0 {
+		b.replyWithMessage(chatID, "There are no orders to export.", nil)
+		return
+	}
+
+	b.bot.Send(tgbotapi.NewChatAction(chatID, tgbotapi.ChatUploadDocument))
+
+	date := time.Now().Format("2006-01-02")
+	csvData, err := buildOrdersCSV(orders)
+	if err != nil {
+		log.Printf("Error building orders CSV: %v", err)
+		b.replyWithMessage(chatID, "Error exporting your orders. Please try again later.", nil)
+		return
+	}
+	b.sendDocument(chatID, fmt.Sprintf("orders_%s.csv", date), csvData)
+	b.sendDocument(chatID, fmt.Sprintf("orders_%s.pdf", date), buildOrdersPDF(orders))
+}
+
+// collectOrdersForExport fetches every page of the order history that matches the filters of the view.
+func (b *Bot) collectOrdersForExport(chatID int64, view *OrderHistoryView) ([]api.OrderResponseItem, error) {
+	query := view.query()
+	query.PerPage = exportPerPage
+
+	var orders []api.OrderResponseItem
+	for page := 1; page <= exportMaxPages; page++ {
+		query.Page = page
+		orderHistory, hasNextPage, err := b.apiClient.GetOrderHistory(query, b.auth, chatID)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, orderHistory.Data...)
+		if !hasNextPage {
+			break
+		}
+	}
+	return orders, nil
+}
+
+// sendDocument uploads data as a document with the given file name.
+func (b *Bot) sendDocument(chatID int64, name string, data []byte) {
+	document := tgbotapi.NewDocumentUpload(chatID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	if _, err := b.bot.Send(document); err != nil {
+		log.Printf("Error sending document %s: %v", name, err)
+		b.replyWithMessage(chatID, fmt.Sprintf("Failed to send %s. Please try again later.", name), nil)
+	}
+}
+
+// buildOrdersCSV writes one CSV row per ordered item, and a row without a product for an order without items.
+func buildOrdersCSV(orders []api.OrderResponseItem) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	header := []string{"Order ID", "Date", "Status", "Product", "Quantity", "Unit Price", "Line Total", "Order Total"}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		date := csvCell(formatExportDate(orderCreatedAt(order)))
+		if len(order.OrderItems) == 0 {
+			record := []string{strconv.Itoa(order.ID), date, csvCell(order.Status), "", "", "", "", fmt.Sprintf("%.2f", order.TotalPrice)}
+			if err := w.Write(record); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, item := range order.OrderItems {
+			record := []string{
+				strconv.Itoa(order.ID),
+				date,
+				csvCell(order.Status),
+				csvCell(item.ProductName),
+				strconv.Itoa(item.Quantity),
+				fmt.Sprintf("%.2f", item.Price),
+				fmt.Sprintf("%.2f", float64(item.Quantity)*item.Price),
+				fmt.Sprintf("%.2f", order.TotalPrice),
+			}
+			if err := w.Write(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// csvCell keeps spreadsheet applications from running a text cell as a formula by prefixing it with a quote.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
+
+// buildOrdersPDF renders the orders as a PDF report with an item table for every order and a grand total.
+func buildOrdersPDF(orders []api.OrderResponseItem) []byte {
+	doc := pdf.New()
+	doc.Heading("Order history", 18)
+	doc.Text(fmt.Sprintf("Generated on %s · %d orders", time.Now().Format("02 January 2006, 15:04"), len(orders)), 10)
+	doc.Space(10)
+
+	widths := []float64{255, 60, 90, 90}
+	grandTotal := 0.0
+	for _, order := range orders {
+		doc.Heading(fmt.Sprintf("Order #%d · %s · %s", order.ID, formatExportDate(orderCreatedAt(order)), strings.ToUpper(order.Status)), 12)
+		doc.Row([]pdf.Column{
+			{Text: "Product", Width: widths[0]},
+			{Text: "Qty", Width: widths[1], AlignRight: true},
+			{Text: "Unit price", Width: widths[2], AlignRight: true},
+			{Text: "Line total", Width: widths[3], AlignRight: true},
+		}, 10, true)
+		doc.Rule()
+		for _, item := range order.OrderItems {
+			doc.Row([]pdf.Column{
+				{Text: item.ProductName, Width: widths[0]},
+				{Text: strconv.Itoa(item.Quantity), Width: widths[1], AlignRight: true},
+				{Text: fmt.Sprintf("$%.2f", item.Price), Width: widths[2], AlignRight: true},
+				{Text: fmt.Sprintf("$%.2f", float64(item.Quantity)*item.Price), Width: widths[3], AlignRight: true},
+			}, 10, false)
+		}
+		doc.Rule()
+		doc.Row([]pdf.Column{
+			{Text: "Order total", Width: widths[0] + widths[1] + widths[2]},
+			{Text: fmt.Sprintf("$%.2f", order.TotalPrice), Width: widths[3], AlignRight: true},
+		}, 10, true)
+		doc.Space(12)
+		grandTotal += order.TotalPrice
+	}
+
+	doc.Rule()
+	doc.Row([]pdf.Column{
+		{Text: "Grand total", Width: widths[0] + widths[1] + widths[2]},
+		{Text: fmt.Sprintf("$%.2f", grandTotal), Width: widths[3], AlignRight: true},
+	}, 12, true)
+
+	return doc.Bytes()
+}
+
+// formatExportDate converts an API timestamp into the date format used in exports.
+func formatExportDate(value string) string {
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return value
+	}
+	return timestamp.Format("2006-01-02 15:04")
+}
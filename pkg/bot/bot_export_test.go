@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"my-telegram-bot/pkg/api"
+)
+
+func TestBuildOrdersCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		orders []api.OrderResponseItem
+		want   [][]string
+	}{
+		{
+			name:   "no orders",
+			orders: nil,
+			want:   nil,
+		},
+		{
+			name: "one row per item",
+			orders: []api.OrderResponseItem{{
+				ID:         7,
+				Status:     "delivered",
+				TotalPrice: 12.5,
+				CreatedAt:  "2024-03-05T14:30:00.000Z",
+				OrderItems: []api.OrderItem{
+					{ProductName: "Milk", Quantity: 2, Price: 1.25},
+					{ProductName: "Bread", Quantity: 1, Price: 10},
+				},
+			}},
+			want: [][]string{
+				{"7", "2024-03-05 14:30", "delivered", "Milk", "2", "1.25", "2.50", "12.50"},
+				{"7", "2024-03-05 14:30", "delivered", "Bread", "1", "10.00", "10.00", "12.50"},
+			},
+		},
+		{
+			name: "special characters and item dates",
+			orders: []api.OrderResponseItem{{
+				ID:         8,
+				Status:     "pending",
+				TotalPrice: 3,
+				OrderItems: []api.OrderItem{
+					{ProductName: `Сыр "Российский", 45%`, Quantity: 3, Price: 1, CreatedAt: "2024-03-06T09:05:00Z"},
+				},
+			}},
+			want: [][]string{
+				{"8", "2024-03-06 09:05", "pending", `Сыр "Российский", 45%`, "3", "1.00", "3.00", "3.00"},
+			},
+		},
+		{
+			name: "order without items",
+			orders: []api.OrderResponseItem{{
+				ID:         10,
+				Status:     "cancelled",
+				TotalPrice: 0,
+				CreatedAt:  "2024-03-07T10:00:00Z",
+			}},
+			want: [][]string{
+				{"10", "2024-03-07 10:00", "cancelled", "", "", "", "", "0.00"},
+			},
+		},
+		{
+			name: "formulas",
+			orders: []api.OrderResponseItem{{
+				ID:         11,
+				Status:     "@status",
+				TotalPrice: 2,
+				CreatedAt:  "=NOW()",
+				OrderItems: []api.OrderItem{
+					{ProductName: "=HYPERLINK(\"http://example.com\")", Quantity: 1, Price: 1},
+					{ProductName: "+1 apple", Quantity: 1, Price: 1},
+					{ProductName: "-50% cheese", Quantity: 1, Price: 0},
+					{ProductName: "Plain - milk", Quantity: 1, Price: 0},
+				},
+			}},
+			want: [][]string{
+				{"11", "'=NOW()", "'@status", "'=HYPERLINK(\"http://example.com\")", "1", "1.00", "1.00", "2.00"},
+				{"11", "'=NOW()", "'@status", "'+1 apple", "1", "1.00", "1.00", "2.00"},
+				{"11", "'=NOW()", "'@status", "'-50% cheese", "1", "0.00", "0.00", "2.00"},
+				{"11", "'=NOW()", "'@status", "Plain - milk", "1", "0.00", "0.00", "2.00"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := buildOrdersCSV(tt.orders)
+			if err != nil {
+				t.Fatalf("buildOrdersCSV: %v", err)
+			}
+			records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+			if err != nil {
+				t.Fatalf("reading CSV: %v", err)
+			}
+			if len(records) == 0 || records[0][0] != "Order ID" || len(records[0]) != 8 {
+				t.Fatalf("unexpected header %q", records)
+			}
+			rows := records[1:]
+			if len(rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d: %q", len(rows), len(tt.want), rows)
+			}
+			for i := range rows {
+				if strings.Join(rows[i], "|") != strings.Join(tt.want[i], "|") {
+					t.Errorf("row %d = %q, want %q", i, rows[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildOrdersPDF(t *testing.T) {
+	orders := []api.OrderResponseItem{{
+		ID:         9,
+		Status:     "delivered",
+		TotalPrice: 4.2,
+		CreatedAt:  "2024-03-05T14:30:00Z",
+		OrderItems: []api.OrderItem{{ProductName: "Кефир", Quantity: 2, Price: 2.1}},
+	}}
+
+	data := buildOrdersPDF(orders)
+	if !bytes.HasPrefix(data, []byte("%PDF-")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatal("output is not a complete PDF file")
+	}
+	// The standard fonts have no Cyrillic, so the product name is replaced rather than garbled
+	if !bytes.Contains(data, []byte("(?????) Tj")) {
+		t.Error("the Cyrillic product name is not replaced with question marks")
+	}
+}
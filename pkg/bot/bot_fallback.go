@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"log"
+	"my-telegram-bot/pkg/api"
+	"my-telegram-bot/pkg/auth"
+	"sync"
+)
+
+// optionalFeature picks between the backend implementation of a feature that not every backend has
+// and the bot's own implementation on top of the store.
+// Support is probed once with an explicit request for the collection of the feature.
+// Errors of single requests, such as a 404 for an address that was deleted meanwhile, never switch it.
+type optionalFeature[T any] struct {
+	name   string
+	remote T
+	local  T
+	probe  func(chatID int64) (bool, error)
+
+	mu        sync.Mutex
+	probed    bool
+	supported bool
+}
+
+// newOptionalFeature creates a feature that is served by remote when the backend has the collection at path.
+func newOptionalFeature[T any](name string, path string, apiClient *api.APIClient, authClient *auth.AuthClient, remote, local T) *optionalFeature[T] {
+	return &optionalFeature[T]{
+		name:   name,
+		remote: remote,
+		local:  local,
+		probe: func(chatID int64) (bool, error) {
+			return apiClient.HasEndpoint(path, authClient, chatID)
+		},
+	}
+}
+
+// get returns the implementation in use, probing the backend on first use.
+// As long as the probe fails the backend implementation is used, so the user sees the error
+// and nothing ends up in the store that the backend doesn't know about.
+func (f *optionalFeature[T]) get(chatID int64) T {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.probed {
+		supported, err := f.probe(chatID)
+		if err != nil {
+			log.Printf("Error checking backend support for %s: %v", f.name, err)
+			return f.remote
+		}
+		if !supported {
+			log.Printf("The backend does not support %s, keeping them in the bot's store", f.name)
+		}
+		f.probed = true
+		f.supported = supported
+	}
+
+	if f.supported {
+		return f.remote
+	}
+	return f.local
+}
@@ -0,0 +1,216 @@
+package bot
+
+import (
+	"log"
+	"my-telegram-bot/pkg/api"
+	"my-telegram-bot/pkg/auth"
+	"my-telegram-bot/pkg/store"
+	"strconv"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// favoritesBucket is the store bucket holding the favorites kept by the bot itself
+const favoritesBucket = "favorites"
+
+// Favorites manages the products the users have bookmarked.
+type Favorites interface {
+	List(chatID int64) ([]api.Product, error)
+	Add(chatID int64, product api.Product) error
+	Remove(chatID int64, productID int) error
+}
+
+// apiFavorites keeps the favorites on the backend.
+type apiFavorites struct {
+	apiClient *api.APIClient
+	auth      *auth.AuthClient
+}
+
+func (a *apiFavorites) List(chatID int64) ([]api.Product, error) {
+	return a.apiClient.GetFavorites(a.auth, chatID)
+}
+
+func (a *apiFavorites) Add(chatID int64, product api.Product) error {
+	return a.apiClient.AddFavorite(product.ID, a.auth, chatID)
+}
+
+func (a *apiFavorites) Remove(chatID int64, productID int) error {
+	return a.apiClient.RemoveFavorite(productID, a.auth, chatID)
+}
+
+// localFavorites keeps the favorites in the bot's own store, together with the product details shown in the list.
+type localFavorites struct {
+	store *store.FileStore
+	mu    sync.Mutex
+}
+
+func (l *localFavorites) load(chatID int64) ([]api.Product, error) {
+	var products []api.Product
+	if _, err := l.store.Get(favoritesBucket, strconv.FormatInt(chatID, 10), &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (l *localFavorites) List(chatID int64) ([]api.Product, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.load(chatID)
+}
+
+func (l *localFavorites) Add(chatID int64, product api.Product) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	products, err := l.load(chatID)
+	if err != nil {
+		return err
+	}
+	for _, existing := range products {
+		if existing.ID == product.ID {
+			return nil
+		}
+	}
+	// The quantity in the cart belongs to the cart, not to the favorite
+	product.InCart = 0
+	products = append(products, product)
+	return l.store.Put(favoritesBucket, strconv.FormatInt(chatID, 10), products)
+}
+
+func (l *localFavorites) Remove(chatID int64, productID int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	products, err := l.load(chatID)
+	if err != nil {
+		return err
+	}
+	var kept []api.Product
+	for _, product := range products {
+		if product.ID != productID {
+			kept = append(kept, product)
+		}
+	}
+	return l.store.Put(favoritesBucket, strconv.FormatInt(chatID, 10), kept)
+}
+
+// fallbackFavorites uses the backend endpoints when the backend has favorites,
+// and the bot's own store otherwise.
+type fallbackFavorites struct {
+	feature *optionalFeature[Favorites]
+}
+
+// newFavorites creates favorites backed by the API, falling back to the store.
+func newFavorites(apiClient *api.APIClient, authClient *auth.AuthClient, fileStore *store.FileStore) Favorites {
+	return &fallbackFavorites{feature: newOptionalFeature[Favorites](
+		"favorites", "/client/favorites", apiClient, authClient,
+		&apiFavorites{apiClient: apiClient, auth: authClient},
+		&localFavorites{store: fileStore},
+	)}
+}
+
+func (f *fallbackFavorites) List(chatID int64) ([]api.Product, error) {
+	return f.feature.get(chatID).List(chatID)
+}
+
+func (f *fallbackFavorites) Add(chatID int64, product api.Product) error {
+	return f.feature.get(chatID).Add(chatID, product)
+}
+
+func (f *fallbackFavorites) Remove(chatID int64, productID int) error {
+	return f.feature.get(chatID).Remove(chatID, productID)
+}
+
+// loadFavorites caches the IDs of the favorite products of the user, which the product cards need to show the ⭐ button.
+func (b *Bot) loadFavorites(chatID int64) map[int]bool {
+	if ids, ok := b.favoriteIDs[chatID]; ok {
+		return ids
+	}
+
+	ids := make(map[int]bool)
+	products, err := b.favorites.List(chatID)
+	if err != nil {
+		// Without the list the cards show every product as not favorite, so try again next time
+		log.Printf("Error fetching favorites: %v", err)
+		return ids
+	}
+	for _, product := range products {
+		ids[product.ID] = true
+	}
+	b.favoriteIDs[chatID] = ids
+	return ids
+}
+
+// isFavorite reports whether the product is in the favorites of the user.
+func (b *Bot) isFavorite(chatID int64, productID int) bool {
+	return b.loadFavorites(chatID)[productID]
+}
+
+// handleToggleFavorite adds the product to the favorites of the user or removes it, and updates the ⭐ button of the card.
+func (b *Bot) handleToggleFavorite(chatID int64, messageID int, productID int) {
+	ids := b.loadFavorites(chatID)
+
+	var err error
+	if ids[productID] {
+		err = b.favorites.Remove(chatID, productID)
+	} else {
+		product, ok := b.products.get(productID)
+		if !ok {
+			product = api.Product{ID: productID}
+		}
+		err = b.favorites.Add(chatID, product)
+	}
+	if err != nil {
+		log.Printf("Error updating favorites: %v", err)
+		b.replyWithMessage(chatID, "Error updating your favorites. Please try again.", nil)
+		return
+	}
+
+	if ids[productID] {
+		delete(ids, productID)
+		b.replyWithMessage(chatID, "Product removed from your favorites.", nil)
+	} else {
+		ids[productID] = true
+		b.replyWithMessage(chatID, "Product added to your favorites ⭐", nil)
+	}
+	b.favoriteIDs[chatID] = ids
+
+	keyboard := b.buildCartKeyboard(chatID, productID)
+	if _, err := b.bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, keyboard)); err != nil {
+		log.Printf("Error refreshing product card: %v", err)
+	}
+}
+
+// handleFavorites sends the favorite products of the user as product cards with add-to-cart controls.
+func (b *Bot) handleFavorites(chatID int64) {
+	products, err := b.favorites.List(chatID)
+	if err != nil {
+		log.Printf("Error fetching favorites: %v", err)
+		b.replyWithMessage(chatID, "Error fetching your favorites. Please try again later.", nil)
+		return
+	}
+	if len(products) == 0 {
+		b.replyWithMessage(chatID, "You have no favorite products yet. Tap ⭐ on a product to save it here.", nil)
+		return
+	}
+
+	if err := b.InitUserCart(chatID); err != nil {
+		log.Printf("Error initializing user cart: %v", err)
+	}
+	b.reconcileCartIfStale(chatID)
+
+	ids := make(map[int]bool)
+	for _, product := range products {
+		ids[product.ID] = true
+	}
+	b.favoriteIDs[chatID] = ids
+
+	b.replyWithMessage(chatID, "Your favorite products ⭐", nil)
+	for _, product := range products {
+		if err := b.sendProductCard(chatID, product); err != nil {
+			b.replyWithMessage(chatID, "An error occurred while sending your favorites. Please try again later.", nil)
+			return
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"my-telegram-bot/pkg/api"
+	"net/http"
+	"testing"
+)
+
+func TestFavoritesFallBackToStore(t *testing.T) {
+	b, _, backend := newTestBot(t)
+
+	if err := b.favorites.Add(testChatID, api.Product{ID: 1, Name: "Milk"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := b.favorites.Add(testChatID, api.Product{ID: 2, Name: "Bread"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := b.favorites.Remove(testChatID, 1); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	products, err := b.favorites.List(testChatID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(products) != 1 || products[0].ID != 2 {
+		t.Errorf("List = %+v, want only Bread", products)
+	}
+	if got := len(backend.received("GET /client/favorites")); got != 1 {
+		t.Errorf("backend was probed %d times, want once", got)
+	}
+	if got := len(backend.received("DELETE /client/favorites/1")); got != 0 {
+		t.Errorf("backend got %d remove requests, want none", got)
+	}
+}
+
+func TestFavoritesUseBackendWhenSupported(t *testing.T) {
+	b, _, backend := newTestBot(t)
+	backend.handleJSON("GET /client/favorites", http.StatusOK, api.FavoritesResponse{Data: []api.Product{{ID: 2, Name: "Bread"}}})
+
+	// A 404 for a single product means it is not a favorite, not that the backend has no favorites
+	if err := b.favorites.Remove(testChatID, 1); err == nil {
+		t.Fatal("removing a missing favorite succeeded")
+	}
+
+	products, err := b.favorites.List(testChatID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(products) != 1 || products[0].ID != 2 {
+		t.Errorf("List = %+v, want the backend favorites", products)
+	}
+	if got := len(backend.received("DELETE /client/favorites/1")); got != 1 {
+		t.Errorf("backend got %d remove requests, want 1", got)
+	}
+}
+
+func TestProductCache(t *testing.T) {
+	cache := newProductCache(2)
+	cache.put(api.Product{ID: 1, Name: "Milk"})
+	cache.put(api.Product{ID: 2, Name: "Bread"})
+	cache.put(api.Product{ID: 1, Name: "Oat milk"})
+	cache.put(api.Product{ID: 3, Name: "Eggs"})
+
+	tests := []struct {
+		id       int
+		wantName string
+		wantOK   bool
+	}{
+		{id: 1, wantOK: false},
+		{id: 2, wantName: "Bread", wantOK: true},
+		{id: 3, wantName: "Eggs", wantOK: true},
+	}
+	for _, tt := range tests {
+		product, ok := cache.get(tt.id)
+		if ok != tt.wantOK || product.Name != tt.wantName {
+			t.Errorf("get(%d) = %q, %v, want %q, %v", tt.id, product.Name, ok, tt.wantName, tt.wantOK)
+		}
+	}
+	if len(cache.products) != 2 || len(cache.order) != 2 {
+		t.Errorf("cache holds %d products in %d slots, want 2", len(cache.products), len(cache.order))
+	}
+}
@@ -0,0 +1,234 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"my-telegram-bot/pkg/api"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// sortOptions lists the sort orders offered in the catalog, in menu order
+var sortOptions = []struct {
+	Sort  string
+	Label string
+}{
+	{"", "Default order"},
+	{api.SortPriceAsc, "Price: low to high"},
+	{api.SortPriceDesc, "Price: high to low"},
+	{api.SortNewest, "Newest first"},
+	{api.SortPopularity, "Most popular"},
+}
+
+// sortLabel returns the menu label of a sort order.
+func sortLabel(sort string) string {
+	for _, option := range sortOptions {
+		if option.Sort == sort {
+			return option.Label
+		}
+	}
+	return sort
+}
+
+// handleSortAction processes the sort buttons of the catalog.
+// The sort menu replaces the paging keyboard, and choosing an order lists the products again from the first page.
+func (b *Bot) handleSortAction(chatID int64, messageID int, data string) {
+	switch {
+	case strings.HasPrefix(data, "sort_menu"):
+		query, ok := b.savedQuery(chatID, strings.TrimPrefix(data, "sort_menu_"))
+		if !ok {
+			b.replyQueryExpired(chatID)
+			return
+		}
+		b.catalogQueries[chatID] = query
+		if err := b.editMessageWithReplyMarkup(chatID, messageID, "Sort products by:", "", buildSortKeyboard(b.catalogQueries[chatID].Sort)); err != nil {
+			log.Printf("Error showing sort options: %v", err)
+		}
+	case strings.HasPrefix(data, "sort_set_"):
+		query := b.catalogQueries[chatID]
+		query.Sort = strings.TrimPrefix(data, "sort_set_")
+		query.Page = 1
+		b.catalogQueries[chatID] = query
+		if err := b.editMessageWithReplyMarkup(chatID, messageID, fmt.Sprintf("Sorted by: %s", sortLabel(query.Sort)), "", tgbotapi.InlineKeyboardMarkup{
+			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+		}); err != nil {
+			log.Printf("Error closing sort options: %v", err)
+		}
+		b.handleMakeOrder(chatID, query)
+	}
+}
+
+// buildSortKeyboard makes the keyboard of the sort menu, marking the current order.
+func buildSortKeyboard(current string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, option := range sortOptions {
+		label := option.Label
+		if option.Sort == current {
+			label = "✅ " + label
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "sort_set_"+option.Sort),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleFilterAction processes the filter buttons of the catalog.
+// The filters are edited on the query kept for the chat and applied once the user asks to show the products.
+func (b *Bot) handleFilterAction(chatID int64, messageID int, data string) {
+	switch {
+	case strings.HasPrefix(data, "filter_menu"):
+		query, ok := b.savedQuery(chatID, strings.TrimPrefix(data, "filter_menu_"))
+		if !ok {
+			b.replyQueryExpired(chatID)
+			return
+		}
+		b.catalogQueries[chatID] = query
+		b.renderFilterMenu(chatID, messageID)
+	case data == "filter_price":
+		b.initUserState(chatID, nil)
+		b.setDataForState(chatID, setCurrentStep, "filter_price")
+		b.replyWithMessage(chatID, "Please enter the price range, for example 5-20, 5- or -20. Send \"any\" to remove the limit:", nil)
+	case data == "filter_weight":
+		b.initUserState(chatID, nil)
+		b.setDataForState(chatID, setCurrentStep, "filter_weight")
+		b.replyWithMessage(chatID, "Please enter the weight range in grams, for example 100-500, 100- or -500. Send \"any\" to remove the limit:", nil)
+	case data == "filter_stock":
+		query := b.catalogQueries[chatID]
+		query.InStock = !query.InStock
+		b.catalogQueries[chatID] = query
+		b.renderFilterMenu(chatID, messageID)
+	case data == "filter_clear":
+		query := b.catalogQueries[chatID]
+		query.MinPrice, query.MaxPrice = 0, 0
+		query.MinWeight, query.MaxWeight = 0, 0
+		query.InStock = false
+		b.catalogQueries[chatID] = query
+		b.renderFilterMenu(chatID, messageID)
+	case data == "filter_apply":
+		query := b.catalogQueries[chatID]
+		query.Page = 1
+		if err := b.editMessageWithReplyMarkup(chatID, messageID, formatFilterMenu(query), "", tgbotapi.InlineKeyboardMarkup{
+			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+		}); err != nil {
+			log.Printf("Error closing filters: %v", err)
+		}
+		b.handleMakeOrder(chatID, query)
+	}
+}
+
+// handleFilterRangeInput reads the price or weight range typed by the user and shows the filters again.
+func (b *Bot) handleFilterRangeInput(msg *tgbotapi.Message, step string) {
+	chatID := msg.Chat.ID
+	min, max, err := parseRange(msg.Text)
+	if err != nil {
+		b.replyWithMessage(chatID, "Please enter a range like 5-20, 5- or -20, or \"any\".", nil)
+		return
+	}
+	b.DeleteUserState(chatID)
+
+	query := b.catalogQueries[chatID]
+	switch step {
+	case "filter_price":
+		query.MinPrice, query.MaxPrice = min, max
+	case "filter_weight":
+		query.MinWeight, query.MaxWeight = int(min), int(max)
+	}
+	b.catalogQueries[chatID] = query
+	b.renderFilterMenu(chatID, 0)
+}
+
+// renderFilterMenu shows the filters of the chat, editing messageID when it is set.
+func (b *Bot) renderFilterMenu(chatID int64, messageID int) {
+	query := b.catalogQueries[chatID]
+	text := formatFilterMenu(query)
+	keyboard := buildFilterKeyboard(query)
+	if messageID != 0 {
+		if err := b.editMessageWithReplyMarkup(chatID, messageID, text, "", keyboard); err != nil {
+			log.Printf("Error showing filters: %v", err)
+		}
+		return
+	}
+	b.sendTextMessageWithReplyMarkup(chatID, text, keyboard)
+}
+
+// formatFilterMenu describes the filters of a query.
+func formatFilterMenu(query api.ProductQuery) string {
+	stock := "all products"
+	if query.InStock {
+		stock = "in stock only"
+	}
+	return fmt.Sprintf("Filters ⚙️\n\nPrice: %s\nWeight: %s\nAvailability: %s",
+		formatPriceRange(query.MinPrice, query.MaxPrice),
+		formatWeightRange(query.MinWeight, query.MaxWeight),
+		stock,
+	)
+}
+
+// buildFilterKeyboard makes the keyboard of the filter menu.
+func buildFilterKeyboard(query api.ProductQuery) tgbotapi.InlineKeyboardMarkup {
+	stock := "❌"
+	if query.InStock {
+		stock = "✅"
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💰 Price: "+formatPriceRange(query.MinPrice, query.MaxPrice), "filter_price"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⚖️ Weight: "+formatWeightRange(query.MinWeight, query.MaxWeight), "filter_weight"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📦 In stock only "+stock, "filter_stock"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🧹 Clear filters", "filter_clear"),
+			tgbotapi.NewInlineKeyboardButtonData("✅ Show products", "filter_apply"),
+		),
+	)
+}
+
+// formatProductQuery summarizes the sort order and the filters of a query for the paging message.
+// It returns an empty string when neither is set.
+func formatProductQuery(query api.ProductQuery) string {
+	var parts []string
+	if query.Sort != "" {
+		parts = append(parts, "Sorted by: "+sortLabel(query.Sort))
+	}
+	if query.MinPrice > 0 || query.MaxPrice > 0 {
+		parts = append(parts, "Price: "+formatPriceRange(query.MinPrice, query.MaxPrice))
+	}
+	if query.MinWeight > 0 || query.MaxWeight > 0 {
+		parts = append(parts, "Weight: "+formatWeightRange(query.MinWeight, query.MaxWeight))
+	}
+	if query.InStock {
+		parts = append(parts, "In stock only")
+	}
+	return strings.Join(parts, "\n")
+}
+
+// formatPriceRange formats a price range for the user.
+func formatPriceRange(min, max float64) string {
+	return formatRange(min, max, func(v float64) string { return fmt.Sprintf("$%.2f", v) })
+}
+
+// formatWeightRange formats a weight range for the user.
+func formatWeightRange(min, max int) string {
+	return formatRange(float64(min), float64(max), func(v float64) string { return strconv.Itoa(int(v)) + " g" })
+}
+
+// formatRange formats a range whose bounds may be unset, using format for each bound.
+func formatRange(min, max float64, format func(float64) string) string {
+	switch {
+	case min > 0 && max > 0:
+		return format(min) + "–" + format(max)
+	case min > 0:
+		return "from " + format(min)
+	case max > 0:
+		return "up to " + format(max)
+	default:
+		return "any"
+	}
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"log"
+	"my-telegram-bot/pkg/store"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// telegramFilesBucket is the store bucket holding the Telegram file_id of each uploaded image, keyed by image URL
+const telegramFilesBucket = "telegram_files"
+
+// imageRegistry remembers the file_id Telegram gives to an image on its first upload,
+// so the image can be sent again without uploading it.
+type imageRegistry struct {
+	store   *store.FileStore
+	mu      sync.Mutex
+	fileIDs map[string]string
+}
+
+func newImageRegistry(fileStore *store.FileStore) *imageRegistry {
+	return &imageRegistry{store: fileStore, fileIDs: make(map[string]string)}
+}
+
+// Get returns the file_id of the image, or an empty string if it has not been uploaded yet.
+func (r *imageRegistry) Get(imageURL string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fileID, ok := r.fileIDs[imageURL]; ok {
+		return fileID
+	}
+
+	var fileID string
+	if _, err := r.store.Get(telegramFilesBucket, imageURL, &fileID); err != nil {
+		log.Printf("Error reading file_id of %s: %v", imageURL, err)
+		return ""
+	}
+	r.fileIDs[imageURL] = fileID
+	return fileID
+}
+
+// Put remembers the file_id of an uploaded image.
+func (r *imageRegistry) Put(imageURL, fileID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fileIDs[imageURL] = fileID
+	if err := r.store.Put(telegramFilesBucket, imageURL, fileID); err != nil {
+		log.Printf("Error saving file_id of %s: %v", imageURL, err)
+	}
+}
+
+// Forget drops the file_id of an image that Telegram no longer accepts.
+func (r *imageRegistry) Forget(imageURL string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.fileIDs, imageURL)
+	if err := r.store.Delete(telegramFilesBucket, imageURL); err != nil {
+		log.Printf("Error deleting file_id of %s: %v", imageURL, err)
+	}
+}
+
+// photoFileID returns the file_id of the largest size of the photo in a sent message.
+func photoFileID(msg tgbotapi.Message) string {
+	if msg.Photo == nil || len(*msg.Photo) == 0 {
+		return ""
+	}
+	photos := *msg.Photo
+	return photos[len(photos)-1].FileID
+}
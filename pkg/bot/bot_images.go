@@ -1,13 +1,18 @@
 package bot
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"mime/multipart"
+	"my-telegram-bot/pkg/imageproc"
 	"net/http"
-	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
@@ -33,88 +38,257 @@ func (b *Bot) downloadImage(fileID string) ([]byte, error) {
 	return data, nil
 }
 
-// getImagePath checks if an image file already exists locally; if not, it downloads the image from imageURL and saves it locally.
-func (b *Bot) getImagePath(imageURL string, entitytype string) (string, error) {
-	filename := filepath.Base(imageURL)
-	var dirname string
-	switch entitytype {
+// imageGroup returns the image cache subdirectory of an entity type.
+func imageGroup(entityType string) string {
+	switch entityType {
 	case "product":
-		dirname = "products"
+		return "products"
 	case "account":
-		dirname = "accounts"
+		return "accounts"
 	default:
-		dirname = "default"
+		return "default"
 	}
-	localImagePath := fmt.Sprintf("images/%s/%s", dirname, filename)
+}
+
+// getImagePath returns the path of the local copy of the image at imageURL, downloading it into the image cache if needed.
+func (b *Bot) getImagePath(imageURL string, entityType string) (string, error) {
+	return b.imageCache.Path(imageURL, imageGroup(entityType))
+}
+
+// registryFileID returns the file_id registered for an image, or an empty string when it has to be uploaded.
+// Only product images are registered: account images can change behind the same URL.
+func (b *Bot) registryFileID(imageURL string, entityType string) string {
+	if entityType != "product" {
+		return ""
+	}
+	return b.imageRegistry.Get(imageURL)
+}
 
-	if _, err := os.Stat(localImagePath); os.IsNotExist(err) {
-		// Create the directory if it does not exist
-		if err := createDirIfNotExist(fmt.Sprintf("images/%s", dirname)); err != nil {
-			return "", fmt.Errorf("creating directory: %w", err)
+// sendImage sends the image to the chat identified by chatID.
+// A product image already uploaded is sent by its file_id, and uploaded again if Telegram rejects the file_id.
+func (b *Bot) sendImage(chatID int64, imageURL string, entityType string) {
+	if fileID := b.registryFileID(imageURL, entityType); fileID != "" {
+		_, err := b.bot.Send(tgbotapi.NewPhotoShare(chatID, fileID))
+		if err == nil {
+			return
 		}
+		log.Printf("Error sending image %s by file_id, uploading it again: %v", imageURL, err)
+		b.imageRegistry.Forget(imageURL)
+	}
 
-		// Download the image and save it to the local folder
-		resp, err := http.Get(imageURL)
-		if err != nil {
-			return "", fmt.Errorf("getting http response: %w", err)
+	localImagePath, err := b.getImagePath(imageURL, entityType)
+	if err != nil {
+		log.Printf("Error getting local image path: %v", err)
+		return
+	}
+
+	// Send  image
+	sentMsg, err := b.bot.Send(tgbotapi.NewPhotoUpload(chatID, localImagePath))
+	if err != nil {
+		log.Printf("Error sending image: %v", err)
+		return
+	}
+	if fileID := photoFileID(sentMsg); fileID != "" && entityType == "product" {
+		b.imageRegistry.Put(imageURL, fileID)
+	}
+}
+
+// maxMediaGroupSize is the largest number of photos Telegram accepts in one media group
+const maxMediaGroupSize = 10
+
+// groupPhoto is a photo of a media group, sent by its file_id when it has one and uploaded from path otherwise
+type groupPhoto struct {
+	URL    string
+	FileID string
+	Path   string
+}
+
+// sendImages sends the images to the chat as media groups, falling back to a single photo when there is only one.
+func (b *Bot) sendImages(chatID int64, imageURLs []string, entityType string) {
+	for len(imageURLs) > 0 {
+		size := len(imageURLs)
+		if size > maxMediaGroupSize {
+			size = maxMediaGroupSize
+			// A media group needs at least two photos, so never leave a single one for the last group
+			if len(imageURLs)-size == 1 {
+				size--
+			}
 		}
-		defer resp.Body.Close()
 
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return "", fmt.Errorf("reading response body: %w", err)
+		if size == 1 {
+			b.sendImage(chatID, imageURLs[0], entityType)
+		} else if err := b.sendImageGroup(chatID, imageURLs[:size], entityType); err != nil {
+			log.Printf("Error sending media group: %v", err)
 		}
+		imageURLs = imageURLs[size:]
+	}
+}
 
-		if err := ioutil.WriteFile(localImagePath, data, 0644); err != nil {
-			return "", fmt.Errorf("writing file: %w", err)
+// sendImageGroup sends the images as one media group, using the registered file_ids of the images uploaded before.
+// If Telegram rejects the group while it refers to file_ids, they are dropped and the whole group is uploaded again.
+func (b *Bot) sendImageGroup(chatID int64, imageURLs []string, entityType string) error {
+	photos := b.groupPhotos(imageURLs, entityType, true)
+	// A media group needs at least two photos, which may no longer be the case if some could not be downloaded
+	if len(photos) < 2 {
+		for _, photo := range photos {
+			b.sendImage(chatID, photo.URL, entityType)
+		}
+		return nil
+	}
+	messages, err := b.sendMediaGroup(chatID, photos)
+	if err != nil {
+		usedFileIDs := false
+		for _, photo := range photos {
+			if photo.FileID != "" {
+				usedFileIDs = true
+				b.imageRegistry.Forget(photo.URL)
+			}
+		}
+		if !usedFileIDs {
+			return err
+		}
+		log.Printf("Error sending media group by file_id, uploading it again: %v", err)
+		photos = b.groupPhotos(imageURLs, entityType, false)
+		if messages, err = b.sendMediaGroup(chatID, photos); err != nil {
+			return err
 		}
 	}
 
-	return localImagePath, nil
+	if entityType == "product" {
+		for i, msg := range messages {
+			if i < len(photos) && photos[i].FileID == "" {
+				if fileID := photoFileID(msg); fileID != "" {
+					b.imageRegistry.Put(photos[i].URL, fileID)
+				}
+			}
+		}
+	}
+	return nil
 }
 
-// createDirIfNotExist checks if a directory exists at the provided path; if not, it creates the directory.
-func createDirIfNotExist(dir string) error {
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("creating directory: %w", err)
+// groupPhotos prepares the photos of a media group, skipping the images that cannot be downloaded.
+func (b *Bot) groupPhotos(imageURLs []string, entityType string, useRegistry bool) []groupPhoto {
+	var photos []groupPhoto
+	for _, imageURL := range imageURLs {
+		if useRegistry {
+			if fileID := b.registryFileID(imageURL, entityType); fileID != "" {
+				photos = append(photos, groupPhoto{URL: imageURL, FileID: fileID})
+				continue
+			}
+		}
+		localImagePath, err := b.getImagePath(imageURL, entityType)
+		if err != nil {
+			log.Printf("Error getting local image path: %v", err)
+			continue
 		}
+		photos = append(photos, groupPhoto{URL: imageURL, Path: localImagePath})
 	}
-	return nil
+	return photos
 }
 
-// sendImage gets a local image path and sends the image to the chat identified by chatID.
-func (b *Bot) sendImage(chatID int64, imageURL string, entityType string) {
-	localImagePath, err := b.getImagePath(imageURL, entityType)
+// sendMediaGroup sends photos as a single media group and returns the sent messages.
+// The Telegram library only sends media groups of URLs, so the upload is built here with attach:// references.
+func (b *Bot) sendMediaGroup(chatID int64, photos []groupPhoto) ([]tgbotapi.Message, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	var media []tgbotapi.InputMediaPhoto
+	for i, photo := range photos {
+		if photo.FileID != "" {
+			media = append(media, tgbotapi.NewInputMediaPhoto(photo.FileID))
+			continue
+		}
+
+		name := fmt.Sprintf("photo%d", i)
+		media = append(media, tgbotapi.NewInputMediaPhoto("attach://"+name))
+
+		data, err := ioutil.ReadFile(photo.Path)
+		if err != nil {
+			return nil, fmt.Errorf("reading image: %w", err)
+		}
+		part, err := w.CreateFormFile(name, filepath.Base(photo.Path))
+		if err != nil {
+			return nil, fmt.Errorf("creating form file: %w", err)
+		}
+		if _, err := part.Write(data); err != nil {
+			return nil, fmt.Errorf("writing form file: %w", err)
+		}
+	}
+
+	mediaJSON, err := json.Marshal(media)
 	if err != nil {
-		log.Printf("Error getting local image path: %v", err)
-		return
+		return nil, fmt.Errorf("encoding media: %w", err)
+	}
+	if err := w.WriteField("chat_id", strconv.FormatInt(chatID, 10)); err != nil {
+		return nil, fmt.Errorf("writing fields: %w", err)
+	}
+	if err := w.WriteField("media", string(mediaJSON)); err != nil {
+		return nil, fmt.Errorf("writing fields: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("closing writer: %w", err)
 	}
 
-	// Send  image
-	if _, err := b.bot.Send(tgbotapi.NewPhotoUpload(chatID, localImagePath)); err != nil {
-		log.Printf("Error sending image: %v", err)
+	resp, err := b.bot.Client.Post(fmt.Sprintf(tgbotapi.APIEndpoint, b.bot.Token, "sendMediaGroup"), w.FormDataContentType(), &body)
+	if err != nil {
+		return nil, fmt.Errorf("sending media group: %w", err)
 	}
-}
+	defer resp.Body.Close()
 
-func (b *Bot) deleteOldImage(name string) {
+	var apiResponse tgbotapi.APIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if !apiResponse.Ok {
+		return nil, errors.New(apiResponse.Description)
+	}
 
-	// Construct old image path
-	oldImgPath := fmt.Sprintf("images/accounts/%s", name)
+	var messages []tgbotapi.Message
+	if err := json.Unmarshal(apiResponse.Result, &messages); err != nil {
+		return nil, fmt.Errorf("decoding sent messages: %w", err)
+	}
+	return messages, nil
+}
 
-	// Delete old image
-	err := os.Remove(oldImgPath)
-	if err != nil && !os.IsNotExist(err) {
-		log.Printf("Error deleting old image %s: %v", oldImgPath, err)
+// deleteOldImage removes the cached copy of a replaced account image.
+func (b *Bot) deleteOldImage(imageURL string) {
+	if err := b.imageCache.Remove(imageURL, imageGroup("account")); err != nil {
+		log.Printf("Error deleting old image %s: %v", imageURL, err)
 	}
+}
+
+// maxImageDocumentSize is the largest file the bot can download from Telegram
+const maxImageDocumentSize = 20 << 20
 
+// hasImage reports whether the message carries a photo or an image sent as a file.
+func hasImage(msg *tgbotapi.Message) bool {
+	return msg.Photo != nil || (msg.Document != nil && strings.HasPrefix(msg.Document.MimeType, "image/"))
 }
 
-func (b *Bot) downloadImageForEditing(msg *tgbotapi.Message) ([]byte, error) {
-	if msg.Photo == nil {
+// downloadProfileImage downloads the photo or the image file of the message and prepares it to become a profile image.
+// Images sent as files keep their original quality and EXIF orientation, unlike the photos Telegram recompresses.
+func (b *Bot) downloadProfileImage(msg *tgbotapi.Message) ([]byte, error) {
+	var fileID string
+	switch {
+	case msg.Photo != nil:
+		photoSize := (*msg.Photo)[len(*msg.Photo)-1]
+		fileID = photoSize.FileID
+	case hasImage(msg):
+		if msg.Document.FileSize > maxImageDocumentSize {
+			return nil, fmt.Errorf("the file is larger than %d MB", maxImageDocumentSize>>20)
+		}
+		fileID = msg.Document.FileID
+	default:
 		return nil, errors.New("no photo found in the message")
 	}
-	photoSize := (*msg.Photo)[len(*msg.Photo)-1]
-	fileID := photoSize.FileID
-	return b.downloadImage(fileID)
+
+	data, err := b.downloadImage(fileID)
+	if err != nil {
+		return nil, err
+	}
+	return imageproc.ProcessProfileImage(data)
+}
+
+func (b *Bot) downloadImageForEditing(msg *tgbotapi.Message) ([]byte, error) {
+	return b.downloadProfileImage(msg)
 }
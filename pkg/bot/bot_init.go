@@ -5,7 +5,12 @@ import (
 	"log"
 	"my-telegram-bot/pkg/api"
 	"my-telegram-bot/pkg/auth"
+	"my-telegram-bot/pkg/geo"
+	"my-telegram-bot/pkg/imagecache"
+	"my-telegram-bot/pkg/search"
+	"my-telegram-bot/pkg/store"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
@@ -14,8 +19,12 @@ const perPage = 5
 
 // UserState stores the current step and data of a user during a register operation
 type UserState struct {
-	CurrentStep string
-	Data        api.RegisterData
+	CurrentStep    string
+	Data           api.RegisterData
+	PendingAddress api.SavedAddress
+	ProductID      int
+	Location       geo.Address
+	LocationFlow   string
 }
 
 // Bot contains the Telegram Bot API, API client, authentication client, user states, and cart
@@ -27,6 +36,31 @@ type Bot struct {
 	states            map[int64]*UserState
 	cart              map[int64]map[int]BotCartItem
 	userEditingStates map[int64]string
+	orderHistoryViews map[int64]*OrderHistoryView
+	checkouts         map[int64]*CheckoutState
+	payments          *PaymentConfig
+	paymentGateway    PaymentGateway
+	cartValidator     CartValidator
+	store             *store.FileStore
+	addressBook       AddressBook
+	geocoder          geo.Geocoder
+	deliveryZones     *geo.Zones
+	appliedPromos     map[int64]*api.PromoCode
+	promoValidator    PromoValidator
+	removedItems      map[int64]*RemovedCartItems
+	cartSyncedAt      map[int64]time.Time
+	favorites         Favorites
+	favoriteIDs       map[int64]map[int]bool
+	products          *productCache
+	categories        []api.Category
+	catalogQueries    map[int64]api.ProductQuery
+	savedQueries      map[int64]*querySet
+	inlineCache       map[string]*inlineResultPage
+	pendingDeepLinks  map[int64]int
+	searchIndex       *search.Index
+	catalogSyncs      chan int64
+	imageRegistry     *imageRegistry
+	imageCache        *imagecache.Cache
 }
 
 type BotCartItem struct {
@@ -46,16 +80,47 @@ func NewBot(token string, apiClient *api.APIClient, authClient *auth.AuthClient)
 
 	log.Printf("Authorized on account %s", bot.Self.UserName)
 
-	b := &Bot{
+	geocoder, err := geo.DefaultGazetteer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load gazetteer: %w", err)
+	}
+
+	return newBot(bot, apiClient, authClient, store.NewFileStore("storage"), geocoder), nil
+}
+
+// newBot wires a Bot around an authorized Telegram client. Tests use it with a Telegram client
+// whose transport is faked and a store in a temporary directory.
+func newBot(bot *tgbotapi.BotAPI, apiClient *api.APIClient, authClient *auth.AuthClient, fileStore *store.FileStore, geocoder geo.Geocoder) *Bot {
+	return &Bot{
 		bot:               bot,
 		apiClient:         apiClient,
 		auth:              authClient,
 		states:            make(map[int64]*UserState),
 		cart:              make(map[int64]map[int]BotCartItem),
 		userEditingStates: make(map[int64]string),
+		orderHistoryViews: make(map[int64]*OrderHistoryView),
+		checkouts:         make(map[int64]*CheckoutState),
+		paymentGateway:    bot,
+		cartValidator:     newCartValidator(apiClient, authClient),
+		store:             fileStore,
+		addressBook:       newAddressBook(apiClient, authClient, fileStore),
+		geocoder:          geocoder,
+		appliedPromos:     make(map[int64]*api.PromoCode),
+		promoValidator:    newPromoValidator(apiClient, authClient),
+		removedItems:      make(map[int64]*RemovedCartItems),
+		cartSyncedAt:      make(map[int64]time.Time),
+		favorites:         newFavorites(apiClient, authClient, fileStore),
+		favoriteIDs:       make(map[int64]map[int]bool),
+		products:          newProductCache(maxRememberedProducts),
+		catalogQueries:    make(map[int64]api.ProductQuery),
+		savedQueries:      make(map[int64]*querySet),
+		inlineCache:       make(map[string]*inlineResultPage),
+		pendingDeepLinks:  make(map[int64]int),
+		searchIndex:       search.NewIndex(),
+		catalogSyncs:      make(chan int64, 1),
+		imageRegistry:     newImageRegistry(fileStore),
+		imageCache:        imagecache.New("images", imagecache.DefaultMaxFileSize, imagecache.DefaultQuota),
 	}
-
-	return b, nil
 }
 
 // Run starts the Bot instance and listens for updates
@@ -67,21 +132,38 @@ func (b *Bot) Run() {
 	if err != nil {
 		log.Fatalf("failed to get updates channel: %v", err)
 	}
+	go b.keepSearchIndexSynced(searchSyncInterval)
 
 	for update := range updates {
-		if update.Message == nil && update.CallbackQuery == nil {
-			continue
-		}
-
-		if update.CallbackQuery != nil {
-			b.handleCallbackQuery(update.CallbackQuery)
-			continue
-		}
-
-		if update.Message.IsCommand() {
-			b.handleCommand(update.Message)
-		} else {
-			b.handleMessage(update.Message)
-		}
+		b.handleUpdate(update)
+	}
+}
+
+// handleUpdate dispatches a single update from Telegram.
+// Recorded update JSON can be unmarshalled into tgbotapi.Update and replayed through it.
+func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	if update.PreCheckoutQuery != nil {
+		b.handlePreCheckoutQuery(update.PreCheckoutQuery)
+		return
+	}
+
+	if update.InlineQuery != nil {
+		b.handleInlineQuery(update.InlineQuery)
+		return
+	}
+
+	if update.Message == nil && update.CallbackQuery == nil {
+		return
+	}
+
+	if update.CallbackQuery != nil {
+		b.handleCallbackQuery(update.CallbackQuery)
+		return
+	}
+
+	if update.Message.IsCommand() {
+		b.handleCommand(update.Message)
+	} else {
+		b.handleMessage(update.Message)
 	}
 }
@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"my-telegram-bot/pkg/api"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const (
+	// inlinePerPage is the number of products returned for each inline query page, Telegram allows up to 50
+	inlinePerPage = 20
+	// inlineCacheTTL is how long the products found for an inline query are reused, by the bot and by Telegram
+	inlineCacheTTL = 5 * time.Minute
+	// productDeepLinkPrefix is the /start parameter opening a product in the bot
+	productDeepLinkPrefix = "product_"
+)
+
+// inlineResultPage is a page of products found for an inline query
+type inlineResultPage struct {
+	Products    []api.Product
+	HasNextPage bool
+	ExpiresAt   time.Time
+}
+
+// handleInlineQuery answers an inline query like "@ourbot cheese" with the matching products.
+// The offset of the query is the catalog page, and each result links back to the product in the bot.
+func (b *Bot) handleInlineQuery(inlineQuery *tgbotapi.InlineQuery) {
+	page := 1
+	if inlineQuery.Offset != "" {
+		if offset, err := strconv.Atoi(inlineQuery.Offset); err == nil && offset > 0 {
+			page = offset
+		}
+	}
+	search := strings.TrimSpace(inlineQuery.Query)
+
+	config := tgbotapi.InlineConfig{
+		InlineQueryID: inlineQuery.ID,
+		Results:       []interface{}{},
+		CacheTime:     int(inlineCacheTTL.Seconds()),
+		IsPersonal:    true,
+	}
+
+	results, err := b.inlineProducts(int64(inlineQuery.From.ID), search, page)
+	if err != nil {
+		// Users who have not registered yet cannot see the catalog, so offer to open the bot instead
+		log.Printf("Error fetching products for inline query: %v", err)
+		config.CacheTime = 0
+		config.SwitchPMText = "Open the shop to see the products"
+		config.SwitchPMParameter = "inline"
+	} else {
+		for _, product := range results.Products {
+			config.Results = append(config.Results, b.inlineProductResult(product))
+		}
+		if results.HasNextPage {
+			config.NextOffset = strconv.Itoa(page + 1)
+		}
+		if page == 1 && len(results.Products) == 0 {
+			config.SwitchPMText = "No products found. Open the shop"
+			config.SwitchPMParameter = "inline"
+		}
+	}
+
+	if _, err := b.bot.AnswerInlineQuery(config); err != nil {
+		log.Printf("Error answering inline query: %v", err)
+	}
+}
+
+// inlineProducts returns a page of the products matching an inline query, reusing the page found recently for the same query.
+// Pages are cached per user, since the products carry the quantities in the cart of the user.
+func (b *Bot) inlineProducts(chatID int64, search string, page int) (*inlineResultPage, error) {
+	key := fmt.Sprintf("%d|%d|%s", chatID, page, strings.ToLower(search))
+	now := time.Now()
+	if cached, ok := b.inlineCache[key]; ok && now.Before(cached.ExpiresAt) {
+		return cached, nil
+	}
+
+	query := api.ProductQuery{Page: page, PerPage: inlinePerPage, Search: search}
+	products, hasNextPage, err := b.apiClient.GetProducts(query, b.auth, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the expired pages so the cache does not grow with every query typed
+	for cachedKey, cached := range b.inlineCache {
+		if now.After(cached.ExpiresAt) {
+			delete(b.inlineCache, cachedKey)
+		}
+	}
+	results := &inlineResultPage{Products: products, HasNextPage: hasNextPage, ExpiresAt: now.Add(inlineCacheTTL)}
+	b.inlineCache[key] = results
+	return results, nil
+}
+
+// inlineProductResult builds the inline result of a product: a photo when the product has a web image, an article otherwise.
+// Both carry a button opening the product in the bot.
+func (b *Bot) inlineProductResult(product api.Product) interface{} {
+	id := strconv.Itoa(product.ID)
+	description := fmt.Sprintf("$%.2f · %d g", product.Price, product.Weight)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonURL("🛍 Open in the shop", b.productDeepLink(product.ID)),
+	))
+
+	if strings.HasPrefix(product.Image, "http://") || strings.HasPrefix(product.Image, "https://") {
+		photo := tgbotapi.NewInlineQueryResultPhotoWithThumb(id, product.Image, product.Image)
+		photo.Title = product.Name
+		photo.Description = description
+		photo.Caption = fmt.Sprintf("%s\n%s", product.Name, description)
+		photo.ReplyMarkup = &keyboard
+		return photo
+	}
+
+	text := fmt.Sprintf("<b>%s</b>\n<b>Price:</b> $%.2f\n<b>Weight:</b> %d g\n%s",
+		html.EscapeString(product.Name), product.Price, product.Weight, html.EscapeString(product.Description))
+	article := tgbotapi.NewInlineQueryResultArticleHTML(id, product.Name, text)
+	article.Description = description
+	article.ReplyMarkup = &keyboard
+	return article
+}
+
+// productDeepLink returns the link that starts the bot on the details of a product.
+func (b *Bot) productDeepLink(productID int) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s%d", b.bot.Self.UserName, productDeepLinkPrefix, productID)
+}
+
+// handleDeepLink opens what a /start parameter points to. It reports whether the product was opened.
+// Users who are not registered yet, or who cannot see the product, get the usual start instead,
+// and the product opens once they have registered.
+func (b *Bot) handleDeepLink(chatID int64, parameter string) bool {
+	if !strings.HasPrefix(parameter, productDeepLinkPrefix) {
+		return false
+	}
+	productID, err := strconv.Atoi(strings.TrimPrefix(parameter, productDeepLinkPrefix))
+	if err != nil {
+		return false
+	}
+	if b.auth.GetToken(chatID) == "" {
+		b.pendingDeepLinks[chatID] = productID
+		return false
+	}
+	product, err := b.apiClient.GetProduct(productID, b.auth, chatID)
+	if err != nil {
+		log.Printf("Error fetching product %d for a deep link: %v", productID, err)
+		b.pendingDeepLinks[chatID] = productID
+		return false
+	}
+	b.showProductDetails(chatID, product)
+	return true
+}
+
+// openPendingDeepLink opens the product of the deep link the user started the bot with before registering.
+func (b *Bot) openPendingDeepLink(chatID int64) {
+	productID, ok := b.pendingDeepLinks[chatID]
+	if !ok {
+		return
+	}
+	delete(b.pendingDeepLinks, chatID)
+	b.handleProductDetails(chatID, productID)
+}
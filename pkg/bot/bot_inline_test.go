@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"my-telegram-bot/pkg/api"
+	"net/http"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+func TestHandleDeepLink(t *testing.T) {
+	tests := []struct {
+		name        string
+		parameter   string
+		registered  bool
+		status      int
+		wantOpened  bool
+		wantPending bool
+	}{
+		{name: "registered user", parameter: "product_5", registered: true, status: http.StatusOK, wantOpened: true},
+		{name: "new user", parameter: "product_5", status: http.StatusOK, wantPending: true},
+		{name: "product not visible", parameter: "product_5", registered: true, status: http.StatusUnauthorized, wantPending: true},
+		{name: "other parameter", parameter: "inline", registered: true, status: http.StatusOK},
+		{name: "bad product ID", parameter: "product_x", registered: true, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, telegram, backend := newTestBot(t)
+			if !tt.registered {
+				delete(b.auth.Tokens, testChatID)
+			}
+			backend.handleJSON("GET /products/5", tt.status, api.ProductResponse{Data: api.Product{ID: 5, Name: "Goat cheese", Price: 6.4}})
+			backend.handleJSON("GET /cart", http.StatusOK, api.CartResponse{})
+
+			if opened := b.handleDeepLink(testChatID, tt.parameter); opened != tt.wantOpened {
+				t.Errorf("handleDeepLink = %v, want %v", opened, tt.wantOpened)
+			}
+			if productID, pending := b.pendingDeepLinks[testChatID]; pending != tt.wantPending || (pending && productID != 5) {
+				t.Errorf("pending deep link = %d, %v, want product 5: %v", productID, pending, tt.wantPending)
+			}
+			if shown := containsText(telegram.texts(), "Goat cheese"); shown != tt.wantOpened {
+				t.Errorf("product shown = %v, want %v", shown, tt.wantOpened)
+			}
+		})
+	}
+}
+
+func TestDeepLinkOpensAfterRegistration(t *testing.T) {
+	b, telegram, backend := newTestBot(t)
+	backend.handleJSON("GET /products/5", http.StatusOK, api.ProductResponse{Data: api.Product{ID: 5, Name: "Goat cheese", Price: 6.4}})
+	backend.handleJSON("GET /cart", http.StatusOK, api.CartResponse{})
+	b.pendingDeepLinks[testChatID] = 5
+
+	b.handleRegistrationSuccess(&tgbotapi.Message{Chat: &tgbotapi.Chat{ID: testChatID}}, api.RegisterData{FirstName: "Sam"})
+	if !containsText(telegram.texts(), "Goat cheese") {
+		t.Errorf("the product was not opened after registration, sent %q", telegram.texts())
+	}
+	if _, pending := b.pendingDeepLinks[testChatID]; pending {
+		t.Error("the deep link is still pending after it was opened")
+	}
+}
+
+func TestInlineProductsCachedPerUser(t *testing.T) {
+	const otherChatID = 2002
+
+	b, _, backend := newTestBot(t)
+	b.auth.SetToken("other-token", otherChatID)
+	backend.handleJSON("GET /products", http.StatusOK, api.ProductsResponse{Data: []api.Product{{ID: 5, Name: "Goat cheese"}}})
+	backend.handleJSON("GET /cart", http.StatusOK, api.CartResponse{})
+
+	for _, chatID := range []int64{testChatID, otherChatID, testChatID} {
+		if _, err := b.inlineProducts(chatID, "Cheese", 1); err != nil {
+			t.Fatalf("inlineProducts(%d): %v", chatID, err)
+		}
+	}
+	// The products carry the cart quantities of the user, which come from the cart of each user
+	if got := len(backend.received("GET /cart")); got != 2 {
+		t.Errorf("backend got %d cart requests, want one per user", got)
+	}
+}
+
+func TestProductDetailsWhenCartFails(t *testing.T) {
+	b, telegram, backend := newTestBot(t)
+	backend.handleJSON("GET /products/5", http.StatusOK, api.ProductResponse{Data: api.Product{ID: 5, Name: "Goat cheese", Price: 6.4}})
+	backend.handleJSON("GET /cart", http.StatusBadGateway, map[string]string{"message": "Bad Gateway"})
+
+	// Both the product button and a deep link end up showing the details
+	b.handleProductDetails(testChatID, 5)
+	if !b.handleDeepLink(testChatID, "product_5") {
+		t.Error("the deep link was not handled")
+	}
+
+	if containsText(telegram.texts(), "Goat cheese") {
+		t.Error("the product was shown without the cart")
+	}
+	if !containsText(telegram.texts(), "Error fetching your cart") {
+		t.Errorf("the user was not told about the error, sent %q", telegram.texts())
+	}
+	if _, ok := b.cart[testChatID]; ok {
+		t.Error("a cart was made up for the user")
+	}
+}
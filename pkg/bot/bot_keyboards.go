@@ -2,7 +2,7 @@ package bot
 
 import (
 	"fmt"
-	"net/url"
+	"my-telegram-bot/pkg/api"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
@@ -70,6 +70,7 @@ func createMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
 		[]tgbotapi.KeyboardButton{
 			tgbotapi.NewKeyboardButton("Order's History 📖"),
 			tgbotapi.NewKeyboardButton("Cart 🛒"),
+			tgbotapi.NewKeyboardButton("Favorites ⭐"),
 		},
 	)
 
@@ -77,24 +78,26 @@ func createMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
 }
 
 // createPaginationKeyboard creates a keyboard with "Previous", "Next", and "Complete Order" buttons.
-func createPaginationKeyboard(page int, hasNextPage bool, search string) tgbotapi.InlineKeyboardMarkup {
+// The buttons refer to the search, category, sort and filters by the ID the query was saved under.
+func createPaginationKeyboard(query api.ProductQuery, queryID int, hasNextPage bool) tgbotapi.InlineKeyboardMarkup {
 	var searchButton tgbotapi.InlineKeyboardButton
-	// URL encode the search query
-	encodedSearch := ""
-	if search != "" {
-		encodedSearch = "_" + url.QueryEscape(search)
-		searchButton = tgbotapi.NewInlineKeyboardButtonData("Searching for: "+search, "search")
+	if query.Search != "" {
+		searchButton = tgbotapi.NewInlineKeyboardButtonData("Searching for: "+query.Search, "search")
 	} else {
 		searchButton = tgbotapi.NewInlineKeyboardButtonData("Search 🔍", "search")
 	}
-	// Create "Previous Page" and "Next Page" buttons
+	// Create "Previous Page" and "Next Page" buttons, keeping the search, category, sort and filters
 	prevPageData := "disabled"
 	nextPageData := "disabled"
-	if page > 1 {
-		prevPageData = fmt.Sprintf("previous_page_%d%s", page, encodedSearch)
+	if query.Page > 1 {
+		prevPageData = fmt.Sprintf("previous_page_%d_%d", query.Page, queryID)
 	}
 	if hasNextPage {
-		nextPageData = fmt.Sprintf("next_page_%d%s", page, encodedSearch)
+		nextPageData = fmt.Sprintf("next_page_%d_%d", query.Page, queryID)
+	}
+	filtersLabel := "Filters ⚙️"
+	if query.MinPrice > 0 || query.MaxPrice > 0 || query.MinWeight > 0 || query.MaxWeight > 0 || query.InStock {
+		filtersLabel = "Filters ⚙️ ✅"
 	}
 
 	return tgbotapi.NewInlineKeyboardMarkup(
@@ -103,6 +106,11 @@ func createPaginationKeyboard(page int, hasNextPage bool, search string) tgbotap
 			searchButton,
 			tgbotapi.NewInlineKeyboardButtonData("Next Page", nextPageData),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Sort ↕️", fmt.Sprintf("sort_menu_%d", queryID)),
+			tgbotapi.NewInlineKeyboardButtonData(filtersLabel, fmt.Sprintf("filter_menu_%d", queryID)),
+			tgbotapi.NewInlineKeyboardButtonData("Categories 📂", "cat_0"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Back ⬅️", "back"),
 			tgbotapi.NewInlineKeyboardButtonData("Complete Order 📦", "complete_order"),
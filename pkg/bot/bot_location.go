@@ -0,0 +1,247 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"my-telegram-bot/pkg/geo"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// addressFields lists the parts of a structured address the user can correct, in display order.
+var addressFields = []struct {
+	Key    string
+	Name   string
+	Prompt string
+}{
+	{"street", "🛣 Street", "Please enter the street:"},
+	{"building", "🏠 Building", "Please enter the building number:"},
+	{"apartment", "🚪 Apartment", "Please enter the apartment, or send '-' to leave it empty:"},
+	{"city", "🏙 City", "Please enter the city:"},
+	{"postcode", "📮 Postcode", "Please enter the postcode, or send '-' to leave it empty:"},
+}
+
+// SetGeocoder replaces the geocoder used to resolve shared locations into addresses and to locate typed addresses.
+func (b *Bot) SetGeocoder(geocoder geo.Geocoder) {
+	b.geocoder = geocoder
+}
+
+// SetDeliveryZones limits the delivery to the given zones. Without zones every address is accepted.
+func (b *Bot) SetDeliveryZones(zones *geo.Zones) {
+	b.deliveryZones = zones
+}
+
+// deliveryZoneRejection checks a delivery address against the delivery zones. It returns the details of the address
+// together with the reason to reject it, or an empty reason to accept it. Without zones every address is accepted.
+// Typed addresses have no coordinates, so they are looked up with the geocoder first and returned with the details found.
+// An address that cannot be found on the map is rejected, as there is no telling whether the shop delivers there.
+func (b *Bot) deliveryZoneRejection(address string, details *geo.Address) (*geo.Address, string) {
+	if b.deliveryZones == nil {
+		return details, ""
+	}
+	if details == nil || !details.HasCoordinates() {
+		located, err := b.locateAddress(address)
+		if err != nil {
+			return details, "Sorry, we couldn't find this address on the map, so we can't tell whether we deliver there 😔"
+		}
+		details = located
+	}
+	if b.deliveryZones.Find(details.Latitude, details.Longitude) != nil {
+		return details, ""
+	}
+
+	reason := "Sorry, we don't deliver to this address yet 😔"
+	if zone, distance := b.deliveryZones.Nearest(details.Latitude, details.Longitude); zone != nil {
+		reason += fmt.Sprintf("\nThe nearest delivery zone is %s, about %s away.", zone.Name, formatDistance(distance))
+	}
+	return details, reason
+}
+
+// locateAddress finds the coordinates of an address typed by the user.
+func (b *Bot) locateAddress(address string) (*geo.Address, error) {
+	if b.geocoder == nil {
+		return nil, geo.ErrNoMatch
+	}
+	located, err := b.geocoder.Forward(address)
+	if err != nil && !errors.Is(err, geo.ErrNoMatch) {
+		log.Printf("Error locating address: %v", err)
+	}
+	return located, err
+}
+
+// handleSharedLocation resolves a shared location into an address and asks the user to confirm it.
+// flow is the step that asked for the address; it decides what happens once the address is confirmed.
+func (b *Bot) handleSharedLocation(msg *tgbotapi.Message, flow string) {
+	chatID := msg.Chat.ID
+	if b.geocoder == nil {
+		// Without a gazetteer there is no address to confirm, the current step takes a typed one
+		b.replyWithMessage(chatID, "Sorry, we can't look up locations at the moment. Please type your address:", tgbotapi.NewRemoveKeyboard(false))
+		return
+	}
+	address := geo.Address{Latitude: msg.Location.Latitude, Longitude: msg.Location.Longitude}
+
+	resolved, err := b.geocoder.Reverse(address.Latitude, address.Longitude)
+	if err == nil {
+		address = *resolved
+	} else if !errors.Is(err, geo.ErrNoMatch) {
+		log.Printf("Error resolving location: %v", err)
+	}
+
+	// Keep the current step so the user can send another address right away
+	if _, reason := b.deliveryZoneRejection(address.String(), &address); reason != "" {
+		b.replyWithMessage(chatID, reason+"\nPlease share another address or location:", createLocationKeyboard())
+		return
+	}
+
+	b.setPendingLocation(chatID, address, flow)
+	b.setDataForState(chatID, setCurrentStep, "address_confirm")
+
+	b.replyWithMessage(chatID, "Location received 📍", tgbotapi.NewRemoveKeyboard(false))
+	b.sendAddressConfirmation(chatID, address)
+}
+
+// sendAddressConfirmation shows the resolved address with buttons to correct each part of it.
+func (b *Bot) sendAddressConfirmation(chatID int64, address geo.Address) {
+	msg := tgbotapi.NewMessage(chatID, formatAddressConfirmation(address))
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = buildAddressConfirmationKeyboard()
+	if _, err := b.bot.Send(msg); err != nil {
+		log.Printf("Error sending address confirmation: %v", err)
+	}
+}
+
+// handleAddressConfirmationAction processes the buttons of the address confirmation.
+func (b *Bot) handleAddressConfirmationAction(chatID int64, messageID int, data string) {
+	if b.GetUserState(chatID) == nil {
+		b.replyWithMessage(chatID, "This address confirmation has expired. Please share your location again.", nil)
+		return
+	}
+	address, _ := b.getPendingLocation(chatID)
+
+	switch {
+	case data == "addr_confirm":
+		if !address.IsComplete() {
+			b.replyWithMessage(chatID, "Please fill in the street, the building and the city before confirming the address.", nil)
+			return
+		}
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf("Address confirmed ✅\n%s", address.String()))
+		if _, err := b.bot.Send(edit); err != nil {
+			log.Printf("Error closing address confirmation: %v", err)
+		}
+		b.completeAddressConfirmation(chatID, address)
+	case strings.HasPrefix(data, "addr_edit_"):
+		key := strings.TrimPrefix(data, "addr_edit_")
+		for _, field := range addressFields {
+			if field.Key == key {
+				b.setDataForState(chatID, setCurrentStep, "addr_"+key)
+				b.replyWithMessage(chatID, field.Prompt, nil)
+				return
+			}
+		}
+	}
+}
+
+// handleAddressFieldInput stores the corrected part of the address and shows the confirmation again.
+func (b *Bot) handleAddressFieldInput(msg *tgbotapi.Message, step string) {
+	chatID := msg.Chat.ID
+	value := strings.TrimSpace(msg.Text)
+	key := strings.TrimPrefix(step, "addr_")
+
+	if value == "-" && (key == "apartment" || key == "postcode") {
+		value = ""
+	} else if value == "" || value == "-" {
+		b.replyWithMessage(chatID, "Value cannot be empty. Please enter a valid value.", nil)
+		return
+	}
+
+	address, flow := b.getPendingLocation(chatID)
+	switch key {
+	case "street":
+		address.Street = value
+	case "building":
+		address.Building = value
+	case "apartment":
+		address.Apartment = value
+	case "city":
+		address.City = value
+	case "postcode":
+		address.Postcode = value
+	}
+
+	b.setPendingLocation(chatID, address, flow)
+	b.setDataForState(chatID, setCurrentStep, "address_confirm")
+	b.sendAddressConfirmation(chatID, address)
+}
+
+// completeAddressConfirmation hands the confirmed address back to the step that asked for it.
+func (b *Bot) completeAddressConfirmation(chatID int64, address geo.Address) {
+	_, flow := b.getPendingLocation(chatID)
+
+	switch flow {
+	case "address":
+		b.setDataForState(chatID, setAddress, address.String())
+		b.setAddressDetails(chatID, &address)
+		b.askForEmail(chatID)
+	case "book_address":
+		pending := b.getPendingAddress(chatID)
+		pending.Address = address.String()
+		pending.AddressDetails = &address
+		b.saveAddressBookEntry(chatID, pending)
+	}
+}
+
+// formatAddressConfirmation builds the HTML text asking the user to confirm the resolved address.
+func formatAddressConfirmation(address geo.Address) string {
+	var sb strings.Builder
+
+	sb.WriteString("<b>Is this your address?</b>\n\n")
+	values := map[string]string{
+		"street":    address.Street,
+		"building":  address.Building,
+		"apartment": address.Apartment,
+		"city":      address.City,
+		"postcode":  address.Postcode,
+	}
+	for _, field := range addressFields {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", field.Name, checkoutValue(values[field.Key])))
+	}
+	if address.HasCoordinates() {
+		sb.WriteString(fmt.Sprintf("📍 Location: %.6f, %.6f\n", address.Latitude, address.Longitude))
+	}
+
+	if address.IsComplete() {
+		sb.WriteString("\nUse the buttons below to correct any detail, then confirm the address.")
+	} else {
+		sb.WriteString("\nWe could not find the full address for this location. Please fill in the missing details, then confirm the address.")
+	}
+	return sb.String()
+}
+
+// buildAddressConfirmationKeyboard makes an inline keyboard with a button per address part and a confirm button.
+func buildAddressConfirmationKeyboard() tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	row := tgbotapi.NewInlineKeyboardRow()
+	for _, field := range addressFields {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("✏️ "+field.Name, "addr_edit_"+field.Key))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = tgbotapi.NewInlineKeyboardRow()
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("✅ Confirm address", "addr_confirm")))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// formatDistance formats a distance in meters for display.
+func formatDistance(meters float64) string {
+	if meters < 1000 {
+		return fmt.Sprintf("%.0f m", meters)
+	}
+	return fmt.Sprintf("%.1f km", meters/1000)
+}
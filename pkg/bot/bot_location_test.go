@@ -0,0 +1,189 @@
+package bot
+
+import (
+	"my-telegram-bot/pkg/geo"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// testDeliveryZones covers the center of Berlin around Friedrichstraße and Alexanderplatz,
+// but not Kurfürstendamm further west.
+const testDeliveryZones = `{
+  "type": "FeatureCollection",
+  "features": [{
+    "type": "Feature",
+    "properties": {"name": "Mitte"},
+    "geometry": {
+      "type": "Polygon",
+      "coordinates": [[[13.37, 52.50], [13.42, 52.50], [13.42, 52.535], [13.37, 52.535], [13.37, 52.50]]]
+    }
+  }]
+}`
+
+// withDeliveryZones limits the delivery of the bot to the test zones and uses the bundled gazetteer.
+func withDeliveryZones(t *testing.T, b *Bot) {
+	t.Helper()
+
+	gazetteer, err := geo.DefaultGazetteer()
+	if err != nil {
+		t.Fatalf("DefaultGazetteer: %v", err)
+	}
+	zones, err := geo.ParseZones([]byte(testDeliveryZones))
+	if err != nil {
+		t.Fatalf("ParseZones: %v", err)
+	}
+	b.SetGeocoder(gazetteer)
+	b.SetDeliveryZones(zones)
+}
+
+func TestDeliveryZoneRejection(t *testing.T) {
+	tests := []struct {
+		name        string
+		noZones     bool
+		address     string
+		details     *geo.Address
+		wantReason  string
+		wantStreet  string
+		wantLocated bool
+	}{
+		{
+			name:        "typed address inside a zone",
+			address:     "Friedrichstraße 100, Berlin",
+			wantStreet:  "Friedrichstraße",
+			wantLocated: true,
+		},
+		{
+			name:       "typed address outside the zones",
+			address:    "Kurfürstendamm 216",
+			wantReason: "The nearest delivery zone is Mitte",
+		},
+		{
+			name:       "typed address that cannot be found",
+			address:    "Baker Street 221b, London",
+			wantReason: "couldn't find this address",
+		},
+		{
+			name:       "details without coordinates are looked up",
+			address:    "Kurfürstendamm 21",
+			details:    &geo.Address{Street: "Kurfürstendamm", Building: "21", City: "Berlin"},
+			wantReason: "we don't deliver to this address",
+		},
+		{
+			name:        "shared location inside a zone",
+			address:     "somewhere",
+			details:     &geo.Address{Latitude: 52.52, Longitude: 13.40},
+			wantLocated: true,
+		},
+		{
+			name:       "shared location outside the zones",
+			address:    "Alexanderplatz 1",
+			details:    &geo.Address{Latitude: 52.48, Longitude: 13.40},
+			wantReason: "we don't deliver to this address",
+		},
+		{
+			name:    "no zones",
+			noZones: true,
+			address: "Baker Street 221b, London",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, _ := newTestBot(t)
+			if !tt.noZones {
+				withDeliveryZones(t, b)
+			}
+
+			details, reason := b.deliveryZoneRejection(tt.address, tt.details)
+			if tt.wantReason == "" && reason != "" {
+				t.Fatalf("address rejected: %q", reason)
+			}
+			if !strings.Contains(reason, tt.wantReason) {
+				t.Errorf("reason = %q, want it to contain %q", reason, tt.wantReason)
+			}
+			if tt.wantLocated && (details == nil || !details.HasCoordinates()) {
+				t.Errorf("details = %+v, want the address with its coordinates", details)
+			}
+			if tt.wantStreet != "" && (details == nil || details.Street != tt.wantStreet) {
+				t.Errorf("details = %+v, want street %q", details, tt.wantStreet)
+			}
+		})
+	}
+}
+
+func TestTypedRegistrationAddressOutsideZones(t *testing.T) {
+	b, telegram, _ := newTestBot(t)
+	withDeliveryZones(t, b)
+	b.initUserState(testChatID, nil)
+	b.setDataForState(testChatID, setCurrentStep, "address")
+
+	update := loadUpdate(t, "successful_payment.json")
+	update.Message.SuccessfulPayment = nil
+	update.Message.Text = "Kurfürstendamm 216, Berlin"
+	b.handleUpdate(update)
+
+	if step := b.GetUserState(testChatID).CurrentStep; step != "address" {
+		t.Errorf("step = %q, want the bot to ask for the address again", step)
+	}
+	if !containsText(telegram.texts(), "we don't deliver to this address") {
+		t.Errorf("the user was not told why, sent %q", telegram.texts())
+	}
+
+	update.Message.Text = "Torstraße 101, Berlin"
+	b.handleUpdate(update)
+
+	state := b.GetUserState(testChatID)
+	if state.CurrentStep != "email" {
+		t.Fatalf("step = %q, want the registration to move on to the email", state.CurrentStep)
+	}
+	if state.Data.Address != "Torstraße 101, Berlin" || state.Data.AddressDetails == nil || !state.Data.AddressDetails.HasCoordinates() {
+		t.Errorf("registration address = %q, %+v, want the typed address with its coordinates", state.Data.Address, state.Data.AddressDetails)
+	}
+}
+
+func TestCheckoutConfirmChecksTypedAddress(t *testing.T) {
+	b, telegram, backend, gateway := newPaymentsTestBot(t)
+	withDeliveryZones(t, b)
+	// The address comes from the account, so it has no details
+	b.checkouts[testChatID].Address = "Kurfürstendamm 216, Berlin"
+
+	b.handleUpdate(loadUpdate(t, "checkout_confirm.json"))
+	if len(gateway.invoices) != 0 {
+		t.Error("an invoice was sent for an address outside the delivery zones")
+	}
+	if !containsText(telegram.texts(), "Please check the delivery address") {
+		t.Errorf("the user was not asked to change the address, sent %q", telegram.texts())
+	}
+
+	b.checkouts[testChatID].Address = "Alexanderplatz 9, Berlin"
+	b.handleUpdate(loadUpdate(t, "checkout_confirm.json"))
+	if len(gateway.invoices) != 1 {
+		t.Fatalf("sent %d invoices, want 1", len(gateway.invoices))
+	}
+	if details := b.checkouts[testChatID].AddressDetails; details == nil || details.Street != "Alexanderplatz" {
+		t.Errorf("checkout details = %+v, want the located address", details)
+	}
+	if len(backend.received("POST /orders")) != 0 {
+		t.Error("the order was placed before the payment")
+	}
+}
+
+func TestSharedLocationWithoutGeocoder(t *testing.T) {
+	b, telegram, _ := newTestBot(t)
+	b.initUserState(testChatID, nil)
+	b.setDataForState(testChatID, setCurrentStep, "address")
+
+	update := loadUpdate(t, "successful_payment.json")
+	update.Message.SuccessfulPayment = nil
+	update.Message.Location = &tgbotapi.Location{Latitude: 52.52, Longitude: 13.40}
+	b.handleUpdate(update)
+
+	if step := b.GetUserState(testChatID).CurrentStep; step != "address" {
+		t.Errorf("step = %q, want the bot to wait for a typed address", step)
+	}
+	if !containsText(telegram.texts(), "Please type your address") {
+		t.Errorf("the user was not asked to type the address, sent %q", telegram.texts())
+	}
+}
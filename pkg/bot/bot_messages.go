@@ -2,6 +2,7 @@ package bot
 
 import (
 	"log"
+	"my-telegram-bot/pkg/api"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
@@ -10,6 +11,10 @@ import (
 func (b *Bot) handleCommand(msg *tgbotapi.Message) {
 	switch msg.Command() {
 	case "start":
+		// Links shared from inline mode start the bot with a parameter pointing to a product
+		if b.handleDeepLink(msg.Chat.ID, msg.CommandArguments()) {
+			return
+		}
 		b.handleStart(msg.Chat.ID)
 	default:
 		b.handleUnknownCommand(msg.Chat.ID)
@@ -18,6 +23,11 @@ func (b *Bot) handleCommand(msg *tgbotapi.Message) {
 
 // handleMessage handles messages received from users.
 func (b *Bot) handleMessage(msg *tgbotapi.Message) {
+	// Handle payments confirmed by Telegram
+	if msg.SuccessfulPayment != nil {
+		b.handleSuccessfulPayment(msg)
+		return
+	}
 	// Handle contact sharing
 	if msg.Contact != nil {
 		b.handleSharedContact(msg)
@@ -32,7 +42,7 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 	// Handle text-based commands
 	switch msg.Text {
 	case "Make Order 🛍️":
-		b.handleMakeOrder(msg.Chat.ID, 1, "")
+		b.handleCategories(msg.Chat.ID, 0, 0)
 	case "My Account 📋":
 		b.handleMyAccount(msg.Chat.ID, nil)
 	case "Order's History 📖":
@@ -41,6 +51,8 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 		b.handleCompleteOrder(msg.Chat.ID, true)
 	case "Cart 🛒":
 		b.handleCartAction(msg.Chat.ID)
+	case "Favorites ⭐":
+		b.handleFavorites(msg.Chat.ID)
 	default:
 		// Handle user state-specific actions
 		state := b.GetUserState(msg.Chat.ID)
@@ -53,7 +65,25 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 			case "image":
 				b.handleImage(msg)
 			case "search":
-				b.handleMakeOrder(msg.Chat.ID, 1, msg.Text)
+				b.handleMakeOrder(msg.Chat.ID, api.ProductQuery{Page: 1, Search: msg.Text})
+			case "filter_price", "filter_weight":
+				b.handleFilterRangeInput(msg, state.CurrentStep)
+			case "order_search":
+				b.handleOrderSearch(msg)
+			case "checkout_address", "checkout_phone", "checkout_comment":
+				b.handleCheckoutInput(msg, state.CurrentStep)
+			case "book_label":
+				b.handleAddressBookLabel(msg)
+			case "book_address":
+				b.handleAddressBookAddress(msg)
+			case "quantity":
+				b.handleQuantityInput(msg)
+			case "promo_code":
+				b.handlePromoCodeInput(msg)
+			case "address_confirm":
+				b.replyWithMessage(msg.Chat.ID, "Please confirm your address using the buttons above, or correct any detail first.", nil)
+			case "addr_street", "addr_building", "addr_apartment", "addr_city", "addr_postcode":
+				b.handleAddressFieldInput(msg, state.CurrentStep)
 			default:
 				b.replyWithMessage(msg.Chat.ID, msg.Text, nil)
 			}
@@ -80,3 +110,13 @@ func (b *Bot) replyWithMessage(chatID int64, text string, markup interface{}) {
 		log.Printf("Error sending message: %v", err)
 	}
 }
+
+// editMessageWithReplyMarkup replaces the text and the inline keyboard of an existing message.
+func (b *Bot) editMessageWithReplyMarkup(chatID int64, messageID int, text string, parseMode string, markup tgbotapi.InlineKeyboardMarkup) error {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = parseMode
+	edit.ReplyMarkup = &markup
+
+	_, err := b.bot.Send(edit)
+	return err
+}
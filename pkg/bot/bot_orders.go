@@ -0,0 +1,479 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"my-telegram-bot/pkg/api"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// cancellableStatuses lists the order statuses in which the customer is still allowed to cancel the order.
+var cancellableStatuses = map[string]bool{
+	"new":        true,
+	"pending":    true,
+	"processing": true,
+}
+
+// isOrderCancellable reports whether an order with the given status can be cancelled.
+func isOrderCancellable(status string) bool {
+	return cancellableStatuses[strings.ToLower(status)]
+}
+
+const ordersPerPage = 5
+
+// The screens an order can be cancelled from. The cancel callbacks carry it so that keeping the order returns there.
+const (
+	cancelFromDetails = "details"
+	cancelFromHistory = "history"
+)
+
+// orderStatusFilters is the cycle of statuses the order history can be filtered by. The empty status shows all orders.
+var orderStatusFilters = []string{"", "new", "pending", "processing", "delivered", "cancelled"}
+
+// orderPeriodFilter is a date range preset for the order history.
+type orderPeriodFilter struct {
+	Label string
+	Days  int
+}
+
+// orderPeriodFilters is the cycle of date ranges the order history can be filtered by.
+var orderPeriodFilters = []orderPeriodFilter{
+	{"All time", 0},
+	{"Last 7 days", 7},
+	{"Last 30 days", 30},
+	{"Last 3 months", 90},
+	{"Last year", 365},
+}
+
+// OrderHistoryView stores the page, the filters and the message of the order history shown to a user
+type OrderHistoryView struct {
+	MessageID int
+	Page      int
+	Status    int // index in orderStatusFilters
+	Period    int // index in orderPeriodFilters
+	Search    string
+}
+
+// hasFilters reports whether any filter is applied to the view.
+func (v *OrderHistoryView) hasFilters() bool {
+	return v.Status != 0 || v.Period != 0 || v.Search != ""
+}
+
+// query converts the view into the parameters of the order history endpoint.
+func (v *OrderHistoryView) query() api.OrderHistoryQuery {
+	query := api.OrderHistoryQuery{
+		Page:        v.Page,
+		PerPage:     ordersPerPage,
+		Status:      orderStatusFilters[v.Status],
+		Search:      v.Search,
+		NewestFirst: true,
+	}
+	if days := orderPeriodFilters[v.Period].Days; days > 0 {
+		query.From = time.Now().AddDate(0, 0, -days)
+	}
+	return query
+}
+
+// getOrderHistoryView returns the order history view of a user, creating a new one if needed.
+func (b *Bot) getOrderHistoryView(chatID int64) *OrderHistoryView {
+	view, ok := b.orderHistoryViews[chatID]
+	if !ok {
+		view = &OrderHistoryView{Page: 1}
+		b.orderHistoryViews[chatID] = view
+	}
+	return view
+}
+
+// handleOrderHistory sends the first page of the order history as a single message that is edited in place afterwards.
+func (b *Bot) handleOrderHistory(chatID int64) {
+	view := &OrderHistoryView{Page: 1}
+	b.orderHistoryViews[chatID] = view
+
+	b.renderOrderHistory(chatID, view)
+	b.sendMenu(chatID)
+}
+
+// renderOrderHistory fetches the page described by the view and shows it in the order history message.
+func (b *Bot) renderOrderHistory(chatID int64, view *OrderHistoryView) {
+	orderHistory, hasNextPage, err := b.apiClient.GetOrderHistory(view.query(), b.auth, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, "Error fetching order history. Please try again later.", nil)
+		return
+	}
+
+	if len(orderHistory.Data) == 0 && view.Page == 1 && !view.hasFilters() {
+		b.replyWithMessage(chatID, "You have no orders yet. Start shopping to see your orders here! 🛍️", nil)
+		return
+	}
+
+	text := formatOrderHistoryPage(orderHistory.Data, view)
+	keyboard := buildOrderHistoryKeyboard(orderHistory.Data, view, hasNextPage)
+
+	if view.MessageID != 0 {
+		if err := b.editMessageWithReplyMarkup(chatID, view.MessageID, text, "HTML", keyboard); err != nil {
+			log.Printf("Error editing order history: %v", err)
+		}
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = keyboard
+	sentMsg, err := b.bot.Send(msg)
+	if err != nil {
+		log.Printf("Error sending order history: %v", err)
+		return
+	}
+	view.MessageID = sentMsg.MessageID
+}
+
+// handleOrderHistoryAction applies a paging or filter action from the order history keyboard.
+func (b *Bot) handleOrderHistoryAction(chatID int64, messageID int, data string) {
+	view := b.getOrderHistoryView(chatID)
+	view.MessageID = messageID
+
+	switch {
+	case strings.HasPrefix(data, "orders_page_"):
+		page, err := strconv.Atoi(strings.TrimPrefix(data, "orders_page_"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+		view.Page = page
+	case data == "orders_status":
+		view.Status = (view.Status + 1) % len(orderStatusFilters)
+		view.Page = 1
+	case data == "orders_period":
+		view.Period = (view.Period + 1) % len(orderPeriodFilters)
+		view.Page = 1
+	case data == "orders_reset":
+		view.Status, view.Period, view.Search, view.Page = 0, 0, "", 1
+	case data == "orders_search":
+		b.initUserState(chatID, nil)
+		b.setDataForState(chatID, setCurrentStep, "order_search")
+		b.replyWithMessage(chatID, "Please enter a product name to search your orders for:", nil)
+		return
+	case data == "orders_export":
+		b.handleOrderExport(chatID, view)
+		return
+	}
+
+	b.renderOrderHistory(chatID, view)
+}
+
+// handleOrderSearch applies the product name typed by the user as an order history filter.
+func (b *Bot) handleOrderSearch(msg *tgbotapi.Message) {
+	b.DeleteUserState(msg.Chat.ID)
+
+	view := b.getOrderHistoryView(msg.Chat.ID)
+	view.Search = strings.TrimSpace(msg.Text)
+	view.Page = 1
+
+	// The results replace the history message, which stays the single message of the view
+	b.renderOrderHistory(msg.Chat.ID, view)
+}
+
+// formatOrderHistoryPage builds the HTML text listing the orders of one history page together with the active filters.
+func formatOrderHistoryPage(orders []api.OrderResponseItem, view *OrderHistoryView) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("<b>Your orders</b> · page %d\n", view.Page))
+	filters := []string{
+		"Status: " + orderStatusLabel(orderStatusFilters[view.Status]),
+		"Period: " + orderPeriodFilters[view.Period].Label,
+	}
+	if view.Search != "" {
+		filters = append(filters, fmt.Sprintf("Search: \"%s\"", html.EscapeString(view.Search)))
+	}
+	sb.WriteString("<i>" + strings.Join(filters, " · ") + "</i>\n")
+
+	if len(orders) == 0 {
+		sb.WriteString("\nNo orders match your filters.")
+		return sb.String()
+	}
+
+	for _, order := range orders {
+		var items []string
+		for _, item := range order.OrderItems {
+			items = append(items, fmt.Sprintf("%d x %s", item.Quantity, html.EscapeString(item.ProductName)))
+		}
+		sb.WriteString(fmt.Sprintf("\n<b>Order #%d</b> 📦 <code>%s</code> · $%.2f\n",
+			order.ID, html.EscapeString(strings.ToUpper(order.Status)), order.TotalPrice))
+		sb.WriteString(fmt.Sprintf("%s\n%s\n", formatOrderTimestamp(orderCreatedAt(order)), strings.Join(items, ", ")))
+	}
+
+	return sb.String()
+}
+
+// buildOrderHistoryKeyboard makes the inline keyboard of the order history message:
+// the actions for every order on the page, the paging buttons and the filters.
+func buildOrderHistoryKeyboard(orders []api.OrderResponseItem, view *OrderHistoryView, hasNextPage bool) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	for _, order := range orders {
+		row := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🔎 #%d", order.ID), fmt.Sprintf("order_details_%d", order.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Again", fmt.Sprintf("reorder_%d", order.ID)),
+		)
+		if isOrderCancellable(order.Status) {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData("✖️ Cancel", fmt.Sprintf("cancel_order_%d_%s", order.ID, cancelFromHistory)))
+		}
+		rows = append(rows, row)
+	}
+
+	prevPageData := "disabled"
+	nextPageData := "disabled"
+	if view.Page > 1 {
+		prevPageData = fmt.Sprintf("orders_page_%d", view.Page-1)
+	}
+	if hasNextPage {
+		nextPageData = fmt.Sprintf("orders_page_%d", view.Page+1)
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ Previous", prevPageData),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Page %d", view.Page), "disabled"),
+			tgbotapi.NewInlineKeyboardButtonData("Next ➡️", nextPageData),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Status: "+orderStatusLabel(orderStatusFilters[view.Status]), "orders_status"),
+			tgbotapi.NewInlineKeyboardButtonData("📅 "+orderPeriodFilters[view.Period].Label, "orders_period"),
+		),
+	)
+
+	lastRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Search 🔍", "orders_search"),
+		tgbotapi.NewInlineKeyboardButtonData("📤 Export", "orders_export"),
+	)
+	if view.hasFilters() {
+		lastRow = append(lastRow, tgbotapi.NewInlineKeyboardButtonData("♻️ Reset filters", "orders_reset"))
+	}
+	rows = append(rows, lastRow)
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// orderStatusLabel returns the label of a status filter.
+func orderStatusLabel(status string) string {
+	if status == "" {
+		return "All"
+	}
+	return strings.ToUpper(status[:1]) + status[1:]
+}
+
+// buildOrderKeyboard makes an inline keyboard with the actions available for an order.
+func buildOrderKeyboard(order api.OrderResponseItem) tgbotapi.InlineKeyboardMarkup {
+	actions := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔁 Order again", fmt.Sprintf("reorder_%d", order.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("🧾 Receipt", fmt.Sprintf("order_receipt_%d", order.ID)),
+	)
+	if isOrderCancellable(order.Status) {
+		actions = append(actions, tgbotapi.NewInlineKeyboardButtonData("✖️ Cancel order", fmt.Sprintf("cancel_order_%d_%s", order.ID, cancelFromDetails)))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		actions,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("⬅️ Back to orders", "orders_back")),
+	)
+}
+
+// buildCancelConfirmationKeyboard makes an inline keyboard asking the user to confirm the cancellation of an order.
+func buildCancelConfirmationKeyboard(orderID int, origin string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Yes, cancel it", fmt.Sprintf("confirm_cancel_order_%d_%s", orderID, origin)),
+			tgbotapi.NewInlineKeyboardButtonData("↩️ Keep order", fmt.Sprintf("keep_order_%d_%s", orderID, origin)),
+		),
+	)
+}
+
+// parseCancelCallback returns the order and the screen of a cancel callback once its prefix is removed.
+// Callbacks sent before the screen was part of them come from the order details.
+func parseCancelCallback(data string) (int, string) {
+	id, origin, _ := strings.Cut(data, "_")
+	orderID, _ := strconv.Atoi(id)
+	if origin != cancelFromHistory {
+		origin = cancelFromDetails
+	}
+	return orderID, origin
+}
+
+// handleOrderDetails fetches a single order and shows its full breakdown in place of the order history.
+func (b *Bot) handleOrderDetails(chatID int64, messageID int, orderID int) {
+	orderResponse, err := b.apiClient.GetOrder(orderID, b.auth, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, "Error fetching order details. Please try again later.", nil)
+		return
+	}
+	b.showOrderDetails(chatID, messageID, orderResponse.Data)
+}
+
+// showOrderDetails replaces the message identified by messageID with the details of the order.
+func (b *Bot) showOrderDetails(chatID int64, messageID int, order api.OrderResponseItem) {
+	if err := b.editMessageWithReplyMarkup(chatID, messageID, formatOrderDetails(order), "HTML", buildOrderKeyboard(order)); err != nil {
+		log.Printf("Error showing order details: %v", err)
+	}
+}
+
+// handleCancelOrderRequest replaces the screen the order was cancelled from with a confirmation prompt.
+func (b *Bot) handleCancelOrderRequest(chatID int64, messageID int, orderID int, origin string) {
+	text := fmt.Sprintf("Do you want to cancel <b>Order #%d</b>?", orderID)
+	if err := b.editMessageWithReplyMarkup(chatID, messageID, text, "HTML", buildCancelConfirmationKeyboard(orderID, origin)); err != nil {
+		log.Printf("Error asking for order cancellation confirmation: %v", err)
+	}
+}
+
+// handleConfirmCancelOrder cancels the order once the user has confirmed it and shows the screen the cancel came from.
+func (b *Bot) handleConfirmCancelOrder(chatID int64, messageID int, orderID int, origin string) {
+	orderResponse, err := b.apiClient.CancelOrder(orderID, b.auth, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, fmt.Sprintf("Error cancelling the order: %v Please try again later.", err), nil)
+		b.handleKeepOrder(chatID, messageID, orderID, origin)
+		return
+	}
+
+	if origin == cancelFromHistory {
+		b.showOrderHistoryPage(chatID, messageID)
+	} else {
+		b.showOrderDetails(chatID, messageID, orderResponse.Data)
+	}
+	b.replyWithMessage(chatID, fmt.Sprintf("Order #%d has been cancelled.", orderID), nil)
+}
+
+// handleKeepOrder puts back the screen the cancel came from: the order history page or the order details.
+func (b *Bot) handleKeepOrder(chatID int64, messageID int, orderID int, origin string) {
+	if origin == cancelFromHistory {
+		b.showOrderHistoryPage(chatID, messageID)
+		return
+	}
+	b.handleOrderDetails(chatID, messageID, orderID)
+}
+
+// showOrderHistoryPage shows the current page of the order history in place of the message identified by messageID.
+func (b *Bot) showOrderHistoryPage(chatID int64, messageID int) {
+	view := b.getOrderHistoryView(chatID)
+	view.MessageID = messageID
+	b.renderOrderHistory(chatID, view)
+}
+
+// handleReorder adds every item of a past order back into the cart, reports the items
+// that are no longer available or whose price has changed, and then shows the refreshed cart.
+func (b *Bot) handleReorder(chatID int64, orderID int) {
+	orderResponse, err := b.apiClient.GetOrder(orderID, b.auth, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, "Error fetching order details. Please try again later.", nil)
+		return
+	}
+	if err := b.InitUserCart(chatID); err != nil {
+		log.Printf("Error initializing user cart: %v", err)
+	}
+
+	var unavailable []string
+	var added []api.OrderItem
+	for _, item := range orderResponse.Data.OrderItems {
+		if err := b.apiClient.AddProductToCart(item.ProductID, item.Quantity, b.auth, chatID); err != nil {
+			reason := err.Error()
+			if apiErr, ok := err.(*api.Error); ok {
+				reason = apiErr.Message
+			}
+			unavailable = append(unavailable, fmt.Sprintf("- %s: %s", item.ProductName, reason))
+			continue
+		}
+		added = append(added, item)
+	}
+
+	cartItems, err := b.apiClient.GetCartItems(b.auth, true, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, "An error occurred while fetching your cart. Please try again.", nil)
+		return
+	}
+	b.syncCartQuantities(chatID, cartItems)
+
+	currentPrices := make(map[int]float64)
+	for _, cartItem := range cartItems {
+		currentPrices[cartItem.ProductID] = cartItem.Price
+	}
+	var priceChanges []string
+	for _, item := range added {
+		price, ok := currentPrices[item.ProductID]
+		if ok && math.Abs(price-item.Price) >= 0.005 {
+			priceChanges = append(priceChanges, fmt.Sprintf("- %s: $%.2f ➜ $%.2f", item.ProductName, item.Price, price))
+		}
+	}
+
+	report := fmt.Sprintf("%d of %d items from order #%d were added to your cart.", len(added), len(orderResponse.Data.OrderItems), orderID)
+	if len(unavailable) > 0 {
+		report += "\n\nThese items could not be added:\n" + strings.Join(unavailable, "\n")
+	}
+	if len(priceChanges) > 0 {
+		report += "\n\nThese prices have changed since your order:\n" + strings.Join(priceChanges, "\n")
+	}
+	b.replyWithMessage(chatID, report, nil)
+
+	if len(cartItems) == 0 {
+		b.replyWithMessage(chatID, "Your cart is empty.", nil)
+		b.sendMenu(chatID)
+		return
+	}
+	b.handleUserCart(cartItems, chatID)
+}
+
+// formatOrderDetails builds the HTML text with the per-item price breakdown, courier and timestamps of an order.
+func formatOrderDetails(order api.OrderResponseItem) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("<b>Order ID:</b> %d 📦\n", order.ID))
+	sb.WriteString(fmt.Sprintf("<b>Status:</b> <code>%s</code>\n", html.EscapeString(strings.ToUpper(order.Status))))
+	sb.WriteString(fmt.Sprintf("<b>Placed:</b> %s\n", formatOrderTimestamp(orderCreatedAt(order))))
+	if order.UpdatedAt != "" {
+		sb.WriteString(fmt.Sprintf("<b>Last update:</b> %s\n", formatOrderTimestamp(order.UpdatedAt)))
+	}
+
+	courier := "Not assigned yet"
+	if order.Courier != nil {
+		courier = strings.TrimSpace(order.Courier.FirstName + " " + order.Courier.LastName)
+		if order.Courier.Phone != "" {
+			courier += fmt.Sprintf(" (%s)", order.Courier.Phone)
+		}
+	} else if order.CourierID != 0 {
+		courier = fmt.Sprintf("Courier #%d", order.CourierID)
+	}
+	sb.WriteString(fmt.Sprintf("<b>Courier:</b> %s\n", html.EscapeString(courier)))
+
+	sb.WriteString("\n<b>Items:</b>\n")
+	for _, item := range order.OrderItems {
+		sb.WriteString(fmt.Sprintf("%s\n    %d x $%.2f = $%.2f\n",
+			html.EscapeString(item.ProductName), item.Quantity, item.Price, float64(item.Quantity)*item.Price))
+	}
+	if order.Discount > 0 {
+		sb.WriteString("\n" + html.EscapeString(formatDiscountLine(order.PromoCode, order.Discount)))
+	}
+	sb.WriteString(fmt.Sprintf("\n<b>Total:</b> $%.2f", order.TotalPrice))
+
+	return sb.String()
+}
+
+// orderCreatedAt returns the creation timestamp of an order, falling back to the timestamp of its first item.
+func orderCreatedAt(order api.OrderResponseItem) string {
+	if order.CreatedAt != "" {
+		return order.CreatedAt
+	}
+	if len(order.OrderItems) > 0 {
+		return order.OrderItems[0].CreatedAt
+	}
+	return ""
+}
+
+// formatOrderTimestamp converts an API timestamp into a human readable date and time.
+func formatOrderTimestamp(value string) string {
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return "unknown"
+	}
+	return timestamp.Format("02 January 2006, 15:04")
+}
@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"my-telegram-bot/pkg/api"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// orderCallback makes the callback of a button pressed on the message with the given ID.
+func orderCallback(data string, messageID int) *tgbotapi.CallbackQuery {
+	return &tgbotapi.CallbackQuery{
+		ID:      "1",
+		Data:    data,
+		Message: &tgbotapi.Message{MessageID: messageID, Chat: &tgbotapi.Chat{ID: testChatID}},
+	}
+}
+
+func TestKeepOrderReturnsToOrigin(t *testing.T) {
+	order := api.OrderResponseItem{ID: 7, Status: "pending", TotalPrice: 12.5, CreatedAt: "2024-03-05T14:30:00.000Z"}
+
+	tests := []struct {
+		name        string
+		button      string
+		wantRequest string
+		wantText    string
+	}{
+		{name: "history", button: "history", wantRequest: "GET /orders", wantText: "Your orders"},
+		{name: "details", button: "details", wantRequest: "GET /orders/7", wantText: "Order ID:"},
+		{name: "button without origin", button: "", wantRequest: "GET /orders/7", wantText: "Order ID:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, telegram, backend := newTestBot(t)
+			backend.handleJSON("GET /orders", http.StatusOK, api.OrderHistoryResponse{Data: []api.OrderResponseItem{order}})
+			backend.handleJSON("GET /orders/7", http.StatusOK, api.OrderResponse{Data: order})
+
+			suffix := "7"
+			if tt.button != "" {
+				suffix += "_" + tt.button
+			}
+			b.handleCallbackQuery(orderCallback("cancel_order_"+suffix, 42))
+			edits := telegram.calls("editMessageText")
+			if len(edits) != 1 || !strings.Contains(edits[0].Get("text"), "Order #7") {
+				t.Fatalf("confirmation = %v, want a prompt naming the order", edits)
+			}
+			if markup := edits[0].Get("reply_markup"); !strings.Contains(markup, "keep_order_"+suffix) {
+				t.Errorf("confirmation keyboard %s does not keep the origin", markup)
+			}
+
+			b.handleCallbackQuery(orderCallback("keep_order_"+suffix, 42))
+			if got := len(backend.received(tt.wantRequest)); got != 1 {
+				t.Errorf("backend received %d %s requests, want 1", got, tt.wantRequest)
+			}
+			edits = telegram.calls("editMessageText")
+			if len(edits) != 2 || edits[1].Get("message_id") != "42" || !strings.Contains(edits[1].Get("text"), tt.wantText) {
+				t.Errorf("keeping the order showed %v, want %q in message 42", edits, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestCancellableStatusesCanBeFiltered(t *testing.T) {
+	filters := make(map[string]bool)
+	for _, status := range orderStatusFilters {
+		filters[status] = true
+	}
+	for status := range cancellableStatuses {
+		if !filters[status] {
+			t.Errorf("orders in the cancellable status %q cannot be filtered", status)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"my-telegram-bot/pkg/api"
+	"my-telegram-bot/pkg/auth"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// PaymentConfig holds the Telegram Payments settings. Any provider token issued by @BotFather works,
+// including the test tokens of the payment providers.
+type PaymentConfig struct {
+	ProviderToken string
+	Currency      string
+}
+
+// PaymentGateway sends invoices and answers pre-checkout queries.
+// It is implemented by *tgbotapi.BotAPI and can be replaced with a fake provider when testing the checkout.
+type PaymentGateway interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	AnswerPreCheckoutQuery(config tgbotapi.PreCheckoutConfig) (tgbotapi.APIResponse, error)
+}
+
+// invoicePayloadPrefix marks the invoices created from the cart
+const invoicePayloadPrefix = "cart"
+
+// CartValidator checks that the cart can still be ordered, with the products in stock at their current prices.
+type CartValidator interface {
+	Validate(chatID int64) error
+}
+
+// apiCartValidator asks the backend to validate the cart.
+type apiCartValidator struct {
+	apiClient *api.APIClient
+	auth      *auth.AuthClient
+}
+
+func (a *apiCartValidator) Validate(chatID int64) error {
+	return a.apiClient.ValidateCart(a.auth, chatID)
+}
+
+// totalOnlyValidator stands in for backends without cart validation.
+// The pre-checkout then only compares the cart total with the invoice.
+type totalOnlyValidator struct{}
+
+func (totalOnlyValidator) Validate(chatID int64) error {
+	return nil
+}
+
+// newCartValidator creates a cart validator backed by the API when the backend has the validation endpoint.
+func newCartValidator(apiClient *api.APIClient, authClient *auth.AuthClient) CartValidator {
+	return &fallbackCartValidator{feature: newOptionalFeature[CartValidator](
+		"cart validation", "/cart/validate", apiClient, authClient,
+		&apiCartValidator{apiClient: apiClient, auth: authClient},
+		totalOnlyValidator{},
+	)}
+}
+
+// fallbackCartValidator validates through the backend when it has the endpoint.
+// Once the endpoint is known to exist, a 404 means a product of the cart is gone.
+type fallbackCartValidator struct {
+	feature *optionalFeature[CartValidator]
+}
+
+func (f *fallbackCartValidator) Validate(chatID int64) error {
+	return f.feature.get(chatID).Validate(chatID)
+}
+
+// EnablePayments makes the checkout send a Telegram invoice instead of placing the order right away.
+func (b *Bot) EnablePayments(providerToken string, currency string) {
+	b.payments = &PaymentConfig{ProviderToken: providerToken, Currency: currency}
+}
+
+// SetPaymentGateway replaces the gateway used to send invoices and answer pre-checkout queries.
+func (b *Bot) SetPaymentGateway(gateway PaymentGateway) {
+	b.paymentGateway = gateway
+}
+
+// sendInvoice sends an invoice with one price line per cart item.
+func (b *Bot) sendInvoice(chatID int64) {
+	cartItems, err := b.apiClient.GetCartItems(b.auth, true, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, "An error occurred while fetching your cart. Please try again.", nil)
+		return
+	}
+	if len(cartItems) == 0 {
+		b.replyWithMessage(chatID, "Your cart is empty. Please add at least one product to the cart before placing an order.", nil)
+		return
+	}
+
+	promo, discount, _ := b.cartDiscount(chatID, cartItems)
+	prices, totalAmount := buildLabeledPrices(cartItems, promo, discount)
+	invoice := tgbotapi.NewInvoice(
+		chatID,
+		fmt.Sprintf("Order from %s", shopName),
+		fmt.Sprintf("%d products, total %s", len(cartItems), formatMinorUnits(totalAmount, b.payments.Currency)),
+		buildInvoicePayload(chatID, totalAmount),
+		b.payments.ProviderToken,
+		"checkout",
+		b.payments.Currency,
+		&prices,
+	)
+	if _, err := b.paymentGateway.Send(invoice); err != nil {
+		log.Printf("Error sending invoice: %v", err)
+		b.replyWithMessage(chatID, "Failed to create the invoice. Please try again later.", nil)
+	}
+}
+
+// handlePreCheckoutQuery re-validates the cart before Telegram charges the user.
+// The payment is only approved when the cart can still be ordered and its total matches the invoice.
+func (b *Bot) handlePreCheckoutQuery(query *tgbotapi.PreCheckoutQuery) {
+	answer := tgbotapi.PreCheckoutConfig{PreCheckoutQueryID: query.ID}
+
+	if reason := b.validatePreCheckout(query); reason != "" {
+		answer.ErrorMessage = reason
+	} else {
+		answer.OK = true
+	}
+
+	if _, err := b.paymentGateway.AnswerPreCheckoutQuery(answer); err != nil {
+		log.Printf("Error answering pre-checkout query: %v", err)
+	}
+}
+
+// validatePreCheckout returns the reason to reject the pre-checkout query, or an empty string to approve it.
+func (b *Bot) validatePreCheckout(query *tgbotapi.PreCheckoutQuery) string {
+	chatID, amount, ok := parseInvoicePayload(query.InvoicePayload)
+	if !ok || query.From == nil || int64(query.From.ID) != chatID {
+		return "This invoice is no longer valid. Please start the checkout again."
+	}
+	if b.payments == nil || query.Currency != b.payments.Currency || query.TotalAmount != amount {
+		return "The invoice does not match your order. Please start the checkout again."
+	}
+
+	// The order is placed with the details of the checkout, so there is nothing to deliver without them
+	state, ok := b.checkouts[chatID]
+	if !ok {
+		return "This checkout has expired. Please start it again."
+	}
+	if _, reason := b.deliveryZoneRejection(state.Address, state.AddressDetails); reason != "" {
+		return reason
+	}
+
+	if err := b.cartValidator.Validate(chatID); err != nil {
+		if apiErr, ok := err.(*api.Error); ok && !errors.Is(err, api.ErrNotFound) {
+			return apiErr.Message
+		}
+		return "Some products are no longer available. Please review your cart."
+	}
+
+	cartItems, err := b.apiClient.GetCartItems(b.auth, true, chatID)
+	if err != nil {
+		return "We could not check your cart. Please try again."
+	}
+	promo, discount, _ := b.cartDiscount(chatID, cartItems)
+	if _, total := buildLabeledPrices(cartItems, promo, discount); total != amount {
+		return "Your cart has changed since the invoice was created. Please start the checkout again."
+	}
+
+	return ""
+}
+
+// handleSuccessfulPayment places the order once Telegram reports that the payment went through.
+func (b *Bot) handleSuccessfulPayment(msg *tgbotapi.Message) {
+	payment := msg.SuccessfulPayment
+
+	// Attach the details collected by the checkout wizard before the invoice was sent
+	var checkout api.CheckoutData
+	if state, ok := b.checkouts[msg.Chat.ID]; ok {
+		checkout = state.checkoutData()
+		delete(b.checkouts, msg.Chat.ID)
+	}
+	checkout.TelegramPaymentChargeID = payment.TelegramPaymentChargeID
+	checkout.ProviderPaymentChargeID = payment.ProviderPaymentChargeID
+
+	// placeOrder leaves the failure to its caller, the user only needs to hear that the payment is safe
+	if err := b.placeOrder(msg.Chat.ID, checkout); err != nil {
+		log.Printf("Order for paid invoice failed (charge %s): %v", payment.TelegramPaymentChargeID, err)
+		b.replyWithMessage(msg.Chat.ID, fmt.Sprintf(
+			"Your payment was received, but we could not register the order. Our team will contact you shortly. Payment ID: %s",
+			payment.TelegramPaymentChargeID,
+		), nil)
+	}
+}
+
+// buildLabeledPrices converts the cart items into invoice lines and returns them with the total in minor units.
+// The discount of the promo code is added as a negative line.
+func buildLabeledPrices(cartItems []api.CartItem, promo *api.PromoCode, discount float64) ([]tgbotapi.LabeledPrice, int) {
+	var prices []tgbotapi.LabeledPrice
+	total := 0
+	for _, item := range cartItems {
+		amount := toMinorUnits(float64(item.Quantity) * item.Price)
+		prices = append(prices, tgbotapi.LabeledPrice{
+			Label:  fmt.Sprintf("%s x %d", item.ProductName, item.Quantity),
+			Amount: amount,
+		})
+		total += amount
+	}
+	if discount > 0 {
+		amount := toMinorUnits(discount)
+		prices = append(prices, tgbotapi.LabeledPrice{
+			Label:  fmt.Sprintf("Discount (%s)", promo.Code),
+			Amount: -amount,
+		})
+		total -= amount
+	}
+	return prices, total
+}
+
+// toMinorUnits converts a price into the smallest currency unit expected by Telegram.
+func toMinorUnits(price float64) int {
+	return int(math.Round(price * 100))
+}
+
+// formatMinorUnits formats an amount in minor units for display.
+func formatMinorUnits(amount int, currency string) string {
+	return fmt.Sprintf("%.2f %s", float64(amount)/100, currency)
+}
+
+// buildInvoicePayload encodes the chat and the expected total into the invoice payload.
+func buildInvoicePayload(chatID int64, amount int) string {
+	return fmt.Sprintf("%s:%d:%d", invoicePayloadPrefix, chatID, amount)
+}
+
+// parseInvoicePayload decodes a payload created by buildInvoicePayload.
+func parseInvoicePayload(payload string) (int64, int, bool) {
+	parts := strings.Split(payload, ":")
+	if len(parts) != 3 || parts[0] != invoicePayloadPrefix {
+		return 0, 0, false
+	}
+	chatID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	amount, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, false
+	}
+	return chatID, amount, true
+}
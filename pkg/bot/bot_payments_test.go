@@ -0,0 +1,264 @@
+package bot
+
+import (
+	"encoding/json"
+	"my-telegram-bot/pkg/api"
+	"net/http"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// fakeGateway records the invoices and the pre-checkout answers instead of sending them to Telegram.
+type fakeGateway struct {
+	invoices []tgbotapi.InvoiceConfig
+	answers  []tgbotapi.PreCheckoutConfig
+}
+
+func (g *fakeGateway) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if invoice, ok := c.(tgbotapi.InvoiceConfig); ok {
+		g.invoices = append(g.invoices, invoice)
+	}
+	return tgbotapi.Message{MessageID: 43}, nil
+}
+
+func (g *fakeGateway) AnswerPreCheckoutQuery(config tgbotapi.PreCheckoutConfig) (tgbotapi.APIResponse, error) {
+	g.answers = append(g.answers, config)
+	return tgbotapi.APIResponse{Ok: true}, nil
+}
+
+// testCart is the cart of the recorded payment, it costs 12.50 USD.
+var testCart = []api.CartItem{
+	{ProductID: 1, Quantity: 2, ProductName: "Milk", Price: 2.5},
+	{ProductID: 2, Quantity: 1, ProductName: "Bread", Price: 7.5},
+}
+
+// newPaymentsTestBot creates a bot with payments enabled, the test cart in the backend
+// and a checkout that is ready to be confirmed.
+func newPaymentsTestBot(t *testing.T) (*Bot, *fakeTelegram, *fakeBackend, *fakeGateway) {
+	b, telegram, backend := newTestBot(t)
+
+	gateway := &fakeGateway{}
+	b.EnablePayments("284685063:TEST:provider", "USD")
+	b.SetPaymentGateway(gateway)
+	b.checkouts[testChatID] = &CheckoutState{
+		MessageID:    42,
+		Address:      "12 Baker Street",
+		Phone:        "+15550100",
+		DeliveryTime: asapDelivery,
+	}
+
+	cart := api.CartResponse{}
+	cart.Data.Products = testCart
+	backend.handleJSON("GET /cart", http.StatusOK, cart)
+	backend.handleJSON("GET /cart/validate", http.StatusMethodNotAllowed, map[string]string{"message": "Method Not Allowed"})
+	backend.handleJSON("POST /cart/validate", http.StatusOK, map[string]string{"status": "ok"})
+	backend.handle("POST /orders", func(w http.ResponseWriter, r *http.Request) {
+		var checkout api.CheckoutData
+		json.NewDecoder(r.Body).Decode(&checkout)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(api.CompleteOrderResponse{Data: api.OrderResponseItem{
+			ID:         77,
+			Status:     "pending",
+			TotalPrice: 12.5,
+			Address:    checkout.Address,
+		}})
+	})
+
+	return b, telegram, backend, gateway
+}
+
+func TestPaymentFlowReplay(t *testing.T) {
+	b, telegram, backend, gateway := newPaymentsTestBot(t)
+
+	b.handleUpdate(loadUpdate(t, "checkout_confirm.json"))
+	if len(gateway.invoices) != 1 {
+		t.Fatalf("sent %d invoices, want 1", len(gateway.invoices))
+	}
+	invoice := gateway.invoices[0]
+	if invoice.Payload != "cart:1001:1250" || invoice.Currency != "USD" {
+		t.Errorf("invoice payload %q in %s, want cart:1001:1250 in USD", invoice.Payload, invoice.Currency)
+	}
+	total := 0
+	for _, price := range *invoice.Prices {
+		total += price.Amount
+	}
+	if total != 1250 {
+		t.Errorf("invoice lines add up to %d, want 1250", total)
+	}
+	if _, ok := b.checkouts[testChatID]; !ok {
+		t.Fatal("checkout details were dropped before the payment")
+	}
+
+	b.handleUpdate(loadUpdate(t, "pre_checkout_query.json"))
+	if len(gateway.answers) != 1 {
+		t.Fatalf("answered %d pre-checkout queries, want 1", len(gateway.answers))
+	}
+	if answer := gateway.answers[0]; !answer.OK || answer.PreCheckoutQueryID != "4381942740018472211" {
+		t.Fatalf("pre-checkout answer = %+v, want an approval of the query", answer)
+	}
+
+	b.handleUpdate(loadUpdate(t, "successful_payment.json"))
+	orders := backend.received("POST /orders")
+	if len(orders) != 1 {
+		t.Fatalf("placed %d orders, want 1", len(orders))
+	}
+	var checkout api.CheckoutData
+	if err := json.Unmarshal(orders[0].Body, &checkout); err != nil {
+		t.Fatalf("decoding order: %v", err)
+	}
+	if checkout.TelegramPaymentChargeID != "6120049310_1001_8831" || checkout.ProviderPaymentChargeID != "ch_3PQ9xKLkdIwHu7ix0fW2TgB1" {
+		t.Errorf("order charge IDs = %q, %q", checkout.TelegramPaymentChargeID, checkout.ProviderPaymentChargeID)
+	}
+	if checkout.Address != "12 Baker Street" || checkout.Phone != "+15550100" {
+		t.Errorf("order lost the checkout details: %+v", checkout)
+	}
+	if _, ok := b.checkouts[testChatID]; ok {
+		t.Error("checkout details were kept after the order was placed")
+	}
+	if !containsText(telegram.texts(), "Order Completed!") {
+		t.Errorf("the user was not told about the order, sent %q", telegram.texts())
+	}
+	if len(telegram.calls("sendDocument")) != 1 {
+		t.Error("the receipt was not sent")
+	}
+}
+
+func TestPreCheckoutQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery)
+		wantOK    bool
+		wantError string
+	}{
+		{
+			name:   "valid cart",
+			setup:  func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {},
+			wantOK: true,
+		},
+		{
+			name: "backend without cart validation",
+			setup: func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {
+				backend.handle("GET /cart/validate", http.NotFound)
+				backend.handle("POST /cart/validate", http.NotFound)
+			},
+			wantOK: true,
+		},
+		{
+			name: "product removed from the catalog",
+			setup: func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {
+				backend.handleJSON("POST /cart/validate", http.StatusNotFound, map[string]string{"message": "Product not found."})
+			},
+			wantError: "Some products are no longer available",
+		},
+		{
+			name: "product out of stock",
+			setup: func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {
+				backend.handleJSON("POST /cart/validate", http.StatusUnprocessableEntity, map[string]interface{}{
+					"errors": map[string][]string{"cart": {"Bread is out of stock."}},
+				})
+			},
+			wantError: "Bread is out of stock.",
+		},
+		{
+			name: "cart changed after the invoice",
+			setup: func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {
+				cart := api.CartResponse{}
+				cart.Data.Products = append([]api.CartItem{{ProductID: 3, Quantity: 1, ProductName: "Eggs", Price: 3}}, testCart...)
+				backend.handleJSON("GET /cart", http.StatusOK, cart)
+			},
+			wantError: "Your cart has changed",
+		},
+		{
+			name: "cart changed without validation endpoint",
+			setup: func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {
+				backend.handle("GET /cart/validate", http.NotFound)
+				backend.handle("POST /cart/validate", http.NotFound)
+				cart := api.CartResponse{}
+				cart.Data.Products = testCart[:1]
+				backend.handleJSON("GET /cart", http.StatusOK, cart)
+			},
+			wantError: "Your cart has changed",
+		},
+		{
+			name: "checkout expired",
+			setup: func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {
+				delete(b.checkouts, testChatID)
+			},
+			wantError: "This checkout has expired",
+		},
+		{
+			name: "typed address outside the delivery zones",
+			setup: func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {
+				withDeliveryZones(t, b)
+				b.checkouts[testChatID].Address = "Kurfürstendamm 216, Berlin"
+			},
+			wantError: "we don't deliver to this address",
+		},
+		{
+			name: "other currency",
+			setup: func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {
+				query.Currency = "EUR"
+			},
+			wantError: "does not match your order",
+		},
+		{
+			name: "invoice of another user",
+			setup: func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {
+				query.From.ID = 2002
+			},
+			wantError: "no longer valid",
+		},
+		{
+			name: "forged payload",
+			setup: func(t *testing.T, b *Bot, backend *fakeBackend, query *tgbotapi.PreCheckoutQuery) {
+				query.InvoicePayload = "cart:1001"
+			},
+			wantError: "no longer valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, backend, gateway := newPaymentsTestBot(t)
+			update := loadUpdate(t, "pre_checkout_query.json")
+			tt.setup(t, b, backend, update.PreCheckoutQuery)
+
+			b.handleUpdate(update)
+			if len(gateway.answers) != 1 {
+				t.Fatalf("answered %d pre-checkout queries, want 1", len(gateway.answers))
+			}
+			answer := gateway.answers[0]
+			if answer.OK != tt.wantOK {
+				t.Fatalf("answer OK = %v (%q), want %v", answer.OK, answer.ErrorMessage, tt.wantOK)
+			}
+			if !strings.Contains(answer.ErrorMessage, tt.wantError) {
+				t.Errorf("answer error = %q, want it to contain %q", answer.ErrorMessage, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestSuccessfulPaymentWhenOrderFails(t *testing.T) {
+	b, telegram, backend, _ := newPaymentsTestBot(t)
+	backend.handleJSON("POST /orders", http.StatusInternalServerError, map[string]string{"message": "Server Error"})
+
+	b.handleUpdate(loadUpdate(t, "successful_payment.json"))
+	if !containsText(telegram.texts(), "Payment ID: 6120049310_1001_8831") {
+		t.Errorf("the user was not given the payment ID, sent %q", telegram.texts())
+	}
+	if containsText(telegram.texts(), "Please try again later") {
+		t.Errorf("the user was asked to order again after paying, sent %q", telegram.texts())
+	}
+}
+
+// containsText reports whether one of the texts contains substr.
+func containsText(texts []string, substr string) bool {
+	for _, text := range texts {
+		if strings.Contains(text, substr) {
+			return true
+		}
+	}
+	return false
+}
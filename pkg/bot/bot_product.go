@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"my-telegram-bot/pkg/api"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// handleProductDetails sends the full view of a product: all its images, the complete details and the related products.
+// The details message carries the cart controls and becomes the product card the cart buttons refer to.
+func (b *Bot) handleProductDetails(chatID int64, productID int) {
+	product, err := b.apiClient.GetProduct(productID, b.auth, chatID)
+	if err != nil {
+		log.Printf("Error fetching product %d: %v", productID, err)
+		b.replyWithMessage(chatID, "Error fetching product details. Please try again later.", nil)
+		return
+	}
+	b.showProductDetails(chatID, product)
+}
+
+// showProductDetails sends the full view of a product that was already fetched.
+func (b *Bot) showProductDetails(chatID int64, product *api.Product) {
+	// The cart controls of the details show the quantity in the cart, so they need the cart first
+	if err := b.InitUserCart(chatID); err != nil {
+		log.Printf("Error initializing user cart: %v", err)
+		b.replyWithMessage(chatID, "Error fetching your cart. Please try again later.", nil)
+		return
+	}
+	b.products.put(*product)
+
+	images := product.Images
+	if len(images) == 0 && product.Image != "" {
+		images = []string{product.Image}
+	}
+	b.sendImages(chatID, images, "product")
+
+	msg := tgbotapi.NewMessage(chatID, formatProductDetails(*product))
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = b.buildCartKeyboard(chatID, product.ID)
+	sentMsg, err := b.bot.Send(msg)
+	if err != nil {
+		log.Printf("Error sending product details: %v", err)
+		return
+	}
+	cartItem := b.cart[chatID][product.ID]
+	cartItem.MessageID = sentMsg.MessageID
+	b.cart[chatID][product.ID] = cartItem
+
+	if len(product.Related) > 0 {
+		b.sendTextMessageWithReplyMarkup(chatID, "You may also like:", buildRelatedProductsKeyboard(product.Related))
+	}
+}
+
+// formatProductDetails builds the HTML text of the full product view.
+func formatProductDetails(product api.Product) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("<b>%s</b>\n\n", html.EscapeString(product.Name)))
+	sb.WriteString(fmt.Sprintf("<b>Price:</b> $%.2f\n", product.Price))
+	if product.Weight > 0 {
+		sb.WriteString(fmt.Sprintf("<b>Weight:</b> %d g\n", product.Weight))
+		sb.WriteString(fmt.Sprintf("<b>Price per kg:</b> $%.2f\n", product.Price/float64(product.Weight)*1000))
+	}
+	if product.Description != "" {
+		sb.WriteString(fmt.Sprintf("\n%s", html.EscapeString(product.Description)))
+	}
+
+	return sb.String()
+}
+
+// buildRelatedProductsKeyboard makes an inline keyboard with a button opening the details of each related product.
+func buildRelatedProductsKeyboard(products []api.Product) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, product := range products {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s — $%.2f", product.Name, product.Price), fmt.Sprintf("details_%d", product.ID)),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"my-telegram-bot/pkg/api"
+	"my-telegram-bot/pkg/auth"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// errPromoCodesUnavailable is returned when the backend has no promo codes.
+// Only the backend can apply a discount to the order it records, so the bot has no codes of its own.
+var errPromoCodesUnavailable = errors.New("promo codes are not available")
+
+// PromoValidator looks up the rules of a promo code.
+type PromoValidator interface {
+	Validate(chatID int64, code string) (*api.PromoCode, error)
+}
+
+// apiPromoValidator checks the promo codes on the backend.
+type apiPromoValidator struct {
+	apiClient *api.APIClient
+	auth      *auth.AuthClient
+}
+
+func (a *apiPromoValidator) Validate(chatID int64, code string) (*api.PromoCode, error) {
+	return a.apiClient.ValidatePromoCode(code, a.auth, chatID)
+}
+
+// noPromoCodes stands in for backends without promo codes and rejects every code.
+type noPromoCodes struct{}
+
+func (noPromoCodes) Validate(chatID int64, code string) (*api.PromoCode, error) {
+	return nil, errPromoCodesUnavailable
+}
+
+// fallbackPromoValidator uses the backend endpoint when the backend has promo codes,
+// and rejects every code otherwise. A 404 for a code the backend doesn't know means the code is invalid.
+type fallbackPromoValidator struct {
+	feature *optionalFeature[PromoValidator]
+}
+
+// newPromoValidator creates a promo validator backed by the API.
+func newPromoValidator(apiClient *api.APIClient, authClient *auth.AuthClient) PromoValidator {
+	return &fallbackPromoValidator{feature: newOptionalFeature[PromoValidator](
+		"promo codes", "/promo-codes/validate", apiClient, authClient,
+		&apiPromoValidator{apiClient: apiClient, auth: authClient},
+		noPromoCodes{},
+	)}
+}
+
+func (f *fallbackPromoValidator) Validate(chatID int64, code string) (*api.PromoCode, error) {
+	return f.feature.get(chatID).Validate(chatID, code)
+}
+
+// handlePromoAction processes the promo code buttons of the cart.
+func (b *Bot) handlePromoAction(chatID int64, data string) {
+	switch data {
+	case "promo_apply":
+		b.initUserState(chatID, nil)
+		b.setDataForState(chatID, setCurrentStep, "promo_code")
+		b.replyWithMessage(chatID, "Please enter your promo code:", nil)
+	case "promo_remove":
+		delete(b.appliedPromos, chatID)
+		b.replyWithMessage(chatID, "Promo code removed.", nil)
+		b.handleCartAction(chatID)
+	}
+}
+
+// handlePromoCodeInput validates the promo code typed by the user and applies it to the cart.
+func (b *Bot) handlePromoCodeInput(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	code := strings.ToUpper(strings.TrimSpace(msg.Text))
+	b.DeleteUserState(chatID)
+	if code == "" {
+		b.replyWithMessage(chatID, "Value cannot be empty. Please enter a valid value.", nil)
+		return
+	}
+
+	promo, err := b.promoValidator.Validate(chatID, code)
+	if err != nil {
+		log.Printf("Error validating promo code %s: %v", code, err)
+		b.replyWithMessage(chatID, promoErrorMessage(err), nil)
+		return
+	}
+
+	cartItems, err := b.apiClient.GetCartItems(b.auth, true, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, "An error occurred while fetching your cart. Please try again.", nil)
+		return
+	}
+	discount, reason := promoDiscount(promo, cartSubtotal(cartItems), time.Now())
+	if reason != "" {
+		b.replyWithMessage(chatID, reason, nil)
+		return
+	}
+
+	b.appliedPromos[chatID] = promo
+	b.replyWithMessage(chatID, fmt.Sprintf("Promo code %s applied ✅ You save $%.2f.", promo.Code, discount), nil)
+	b.handleUserCart(cartItems, chatID)
+}
+
+// minOrderTotal is the least an order costs after its discount.
+// Telegram rejects invoices with a zero total, and providers have a minimum charge of about one dollar.
+const minOrderTotal = 1.00
+
+// cartDiscount returns the promo code applied to the cart and the discount it gives for the cart items.
+// When the code no longer applies, the discount is zero and reason tells why.
+func (b *Bot) cartDiscount(chatID int64, cartItems []api.CartItem) (promo *api.PromoCode, discount float64, reason string) {
+	promo = b.appliedPromos[chatID]
+	if promo == nil {
+		return nil, 0, ""
+	}
+	discount, reason = promoDiscount(promo, cartSubtotal(cartItems), time.Now())
+	return promo, discount, reason
+}
+
+// promoDiscount applies the rules of a promo code to the subtotal of the cart.
+// It returns the discount, or the reason why the code cannot be applied.
+func promoDiscount(promo *api.PromoCode, subtotal float64, now time.Time) (float64, string) {
+	if promo.ExpiresAt != nil && now.After(*promo.ExpiresAt) {
+		return 0, fmt.Sprintf("Promo code %s has expired.", promo.Code)
+	}
+	if subtotal < promo.MinOrderAmount {
+		return 0, fmt.Sprintf("Promo code %s requires a minimum order of $%.2f.", promo.Code, promo.MinOrderAmount)
+	}
+
+	var discount float64
+	switch promo.Type {
+	case api.PromoTypePercentage:
+		discount = subtotal * promo.Value / 100
+	case api.PromoTypeFixed:
+		discount = promo.Value
+	default:
+		return 0, fmt.Sprintf("Promo code %s is not valid.", promo.Code)
+	}
+
+	// The discount always leaves minOrderTotal to pay
+	discount = math.Min(math.Round(discount*100)/100, math.Round((subtotal-minOrderTotal)*100)/100)
+	if discount <= 0 {
+		return 0, fmt.Sprintf("Promo code %s cannot be applied to this order.", promo.Code)
+	}
+	return discount, ""
+}
+
+// cartSubtotal returns the cost of the cart items before any discount.
+func cartSubtotal(cartItems []api.CartItem) float64 {
+	subtotal := 0.0
+	for _, item := range cartItems {
+		subtotal += float64(item.Quantity) * item.Price
+	}
+	return subtotal
+}
+
+// formatDiscountLine formats the discount of a promo code as a line of the cart total.
+func formatDiscountLine(code string, discount float64) string {
+	return fmt.Sprintf("Discount (%s): -$%.2f", code, discount)
+}
+
+// promoErrorMessage turns an error from the promo validation into a message for the user.
+func promoErrorMessage(err error) string {
+	if errors.Is(err, errPromoCodesUnavailable) {
+		return "Promo codes are not available at the moment."
+	}
+	if errors.Is(err, api.ErrNotFound) {
+		return "This promo code does not exist."
+	}
+	if apiErr, ok := err.(*api.Error); ok {
+		if ve, ok := apiErr.Details.(*api.ValidationError); ok {
+			if messages := ve.Errors["code"]; len(messages) > 0 {
+				return messages[0]
+			}
+			if ve.Message != "" {
+				return ve.Message
+			}
+		}
+	}
+	return "We could not check the promo code. Please try again later."
+}
+
+// attachPromoCode adds the applied promo code to the order if it still applies to the cart.
+func (b *Bot) attachPromoCode(chatID int64, promo *api.PromoCode, checkout *api.CheckoutData) {
+	cartItems, err := b.apiClient.GetCartItems(b.auth, true, chatID)
+	if err != nil {
+		// The backend checks the code again when it completes the order
+		log.Printf("Error fetching cart for promo code %s: %v", promo.Code, err)
+		checkout.PromoCode = promo.Code
+		return
+	}
+	if _, discount, _ := b.cartDiscount(chatID, cartItems); discount > 0 {
+		checkout.PromoCode = promo.Code
+	}
+}
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"encoding/json"
+	"my-telegram-bot/pkg/api"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPromoDiscount(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name       string
+		promo      api.PromoCode
+		subtotal   float64
+		want       float64
+		wantReason string
+	}{
+		{
+			name:     "percentage",
+			promo:    api.PromoCode{Code: "SAVE10", Type: api.PromoTypePercentage, Value: 10},
+			subtotal: 12.5,
+			want:     1.25,
+		},
+		{
+			name:     "fixed",
+			promo:    api.PromoCode{Code: "FIVE", Type: api.PromoTypeFixed, Value: 5},
+			subtotal: 12.5,
+			want:     5,
+		},
+		{
+			name:     "fixed above the subtotal leaves the minimum total",
+			promo:    api.PromoCode{Code: "FIFTY", Type: api.PromoTypeFixed, Value: 50},
+			subtotal: 12.5,
+			want:     11.5,
+		},
+		{
+			name:     "full percentage leaves the minimum total",
+			promo:    api.PromoCode{Code: "FREE", Type: api.PromoTypePercentage, Value: 100},
+			subtotal: 12.5,
+			want:     11.5,
+		},
+		{
+			name:       "subtotal at the minimum total",
+			promo:      api.PromoCode{Code: "FIVE", Type: api.PromoTypeFixed, Value: 5},
+			subtotal:   minOrderTotal,
+			wantReason: "cannot be applied",
+		},
+		{
+			name:       "expired",
+			promo:      api.PromoCode{Code: "OLD", Type: api.PromoTypeFixed, Value: 5, ExpiresAt: &yesterday},
+			subtotal:   12.5,
+			wantReason: "has expired",
+		},
+		{
+			name:       "below the minimum order",
+			promo:      api.PromoCode{Code: "BIG", Type: api.PromoTypeFixed, Value: 5, MinOrderAmount: 20},
+			subtotal:   12.5,
+			wantReason: "minimum order of $20.00",
+		},
+		{
+			name:       "unknown type",
+			promo:      api.PromoCode{Code: "ODD", Type: "bogo", Value: 1},
+			subtotal:   12.5,
+			wantReason: "is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := promoDiscount(&tt.promo, tt.subtotal, now)
+			if got != tt.want {
+				t.Errorf("discount = %.2f, want %.2f", got, tt.want)
+			}
+			if !strings.Contains(reason, tt.wantReason) || (tt.wantReason == "" && reason != "") {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPlaceOrderPromoCode(t *testing.T) {
+	tests := []struct {
+		name          string
+		promo         api.PromoCode
+		payments      bool
+		wantPromoCode string
+	}{
+		{
+			name:          "code that applies",
+			promo:         api.PromoCode{Code: "SAVE10", Type: api.PromoTypePercentage, Value: 10},
+			wantPromoCode: "SAVE10",
+		},
+		{
+			name:          "code paid online",
+			promo:         api.PromoCode{Code: "SAVE10", Type: api.PromoTypePercentage, Value: 10},
+			payments:      true,
+			wantPromoCode: "SAVE10",
+		},
+		{
+			name:  "code that no longer applies",
+			promo: api.PromoCode{Code: "BIG", Type: api.PromoTypeFixed, Value: 5, MinOrderAmount: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, backend := newTestBot(t)
+			if tt.payments {
+				b.EnablePayments("284685063:TEST:provider", "USD")
+			}
+			promo := tt.promo
+			b.appliedPromos[testChatID] = &promo
+
+			cart := api.CartResponse{}
+			cart.Data.Products = testCart
+			backend.handleJSON("GET /cart", http.StatusOK, cart)
+			backend.handleJSON("POST /orders", http.StatusCreated, api.CompleteOrderResponse{Data: api.OrderResponseItem{ID: 77}})
+
+			if err := b.placeOrder(testChatID, api.CheckoutData{Address: "12 Baker Street", Comment: "Ring twice"}); err != nil {
+				t.Fatalf("placeOrder: %v", err)
+			}
+			orders := backend.received("POST /orders")
+			if len(orders) != 1 {
+				t.Fatalf("placed %d orders, want 1", len(orders))
+			}
+			var checkout api.CheckoutData
+			if err := json.Unmarshal(orders[0].Body, &checkout); err != nil {
+				t.Fatalf("decoding order: %v", err)
+			}
+			if checkout.PromoCode != tt.wantPromoCode {
+				t.Errorf("order promo code = %q, want %q", checkout.PromoCode, tt.wantPromoCode)
+			}
+			if checkout.Comment != "Ring twice" {
+				t.Errorf("order comment = %q, want it unchanged", checkout.Comment)
+			}
+		})
+	}
+}
+
+func TestPromoValidator(t *testing.T) {
+	tests := []struct {
+		name        string
+		backend     func(backend *fakeBackend)
+		wantMessage string
+	}{
+		{
+			name: "backend code",
+			backend: func(backend *fakeBackend) {
+				backend.handleJSON("GET /promo-codes/validate", http.StatusMethodNotAllowed, map[string]string{"message": "Method Not Allowed"})
+				backend.handleJSON("POST /promo-codes/validate", http.StatusOK, api.PromoCodeResponse{Data: api.PromoCode{Code: "HOUSE", Type: api.PromoTypeFixed, Value: 3}})
+			},
+		},
+		{
+			name: "code unknown to the backend",
+			backend: func(backend *fakeBackend) {
+				backend.handleJSON("GET /promo-codes/validate", http.StatusMethodNotAllowed, map[string]string{"message": "Method Not Allowed"})
+			},
+			wantMessage: "This promo code does not exist.",
+		},
+		{
+			name:        "backend without promo codes",
+			backend:     func(backend *fakeBackend) {},
+			wantMessage: "Promo codes are not available at the moment.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, backend := newTestBot(t)
+			tt.backend(backend)
+
+			promo, err := b.promoValidator.Validate(testChatID, "HOUSE")
+			if tt.wantMessage != "" {
+				if err == nil {
+					t.Fatalf("Validate = %+v, want an error", promo)
+				}
+				if got := promoErrorMessage(err); got != tt.wantMessage {
+					t.Errorf("message = %q, want %q", got, tt.wantMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if promo.Code != "HOUSE" || promo.Value != 3 {
+				t.Errorf("Validate = %+v, want the backend code", promo)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"my-telegram-bot/pkg/api"
+	"my-telegram-bot/pkg/pdf"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// shopName is printed in the header of the receipts
+const shopName = "My Telegram Shop"
+
+// sendReceipt generates a PDF receipt for the order and sends it as a document.
+func (b *Bot) sendReceipt(chatID int64, order api.OrderResponseItem) {
+	accountInfo, err := b.apiClient.GetAccountInfo(b.auth, chatID)
+	if err != nil {
+		// The receipt is still useful without the customer block
+		log.Printf("Error fetching account info for receipt: %v", err)
+		accountInfo = nil
+	}
+
+	b.sendDocument(chatID, fmt.Sprintf("receipt_%d.pdf", order.ID), buildReceiptPDF(order, accountInfo))
+}
+
+// handleOrderReceipt fetches an order and sends its receipt.
+func (b *Bot) handleOrderReceipt(chatID int64, orderID int) {
+	orderResponse, err := b.apiClient.GetOrder(orderID, b.auth, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, "Error fetching order details. Please try again later.", nil)
+		return
+	}
+	b.sendReceipt(chatID, orderResponse.Data)
+}
+
+// buildReceiptPDF renders the receipt of an order with the shop header, the customer and delivery details,
+// the line items and the totals.
+func buildReceiptPDF(order api.OrderResponseItem, accountInfo *api.AccountInfo) []byte {
+	date := time.Now()
+	if timestamp, err := time.Parse(time.RFC3339Nano, orderCreatedAt(order)); err == nil {
+		date = timestamp
+	}
+
+	doc := pdf.New()
+	doc.Heading(shopName, 20)
+	doc.Text("Receipt", 12)
+	doc.Rule()
+	doc.Space(6)
+
+	doc.Row([]pdf.Column{{Text: "Order ID:", Width: 100}, {Text: strconv.Itoa(order.ID), Width: 395}}, 10, false)
+	doc.Row([]pdf.Column{{Text: "Date:", Width: 100}, {Text: date.Format("02 January 2006, 15:04"), Width: 395}}, 10, false)
+	doc.Row([]pdf.Column{{Text: "Status:", Width: 100}, {Text: strings.ToUpper(order.Status), Width: 395}}, 10, false)
+
+	if accountInfo != nil || order.Address != "" {
+		doc.Space(10)
+		doc.Heading("Customer", 12)
+	}
+	if accountInfo != nil {
+		customer := strings.TrimSpace(accountInfo.Data.FirstName + " " + accountInfo.Data.LastName)
+		doc.Row([]pdf.Column{{Text: "Name:", Width: 100}, {Text: customer, Width: 395}}, 10, false)
+	}
+	// The order is delivered to the address chosen at checkout, which may differ from the account address
+	if order.Address != "" {
+		doc.Row([]pdf.Column{{Text: "Delivery address:", Width: 100}, {Text: order.Address, Width: 395}}, 10, false)
+	}
+	if accountInfo != nil {
+		doc.Row([]pdf.Column{{Text: "Phone:", Width: 100}, {Text: accountInfo.Data.Phone, Width: 395}}, 10, false)
+		doc.Row([]pdf.Column{{Text: "Email:", Width: 100}, {Text: accountInfo.Data.Email, Width: 395}}, 10, false)
+	}
+
+	doc.Space(10)
+	widths := []float64{255, 60, 90, 90}
+	doc.Row([]pdf.Column{
+		{Text: "Item", Width: widths[0]},
+		{Text: "Qty", Width: widths[1], AlignRight: true},
+		{Text: "Unit price", Width: widths[2], AlignRight: true},
+		{Text: "Amount", Width: widths[3], AlignRight: true},
+	}, 10, true)
+	doc.Rule()
+
+	subtotal := 0.0
+	for _, item := range order.OrderItems {
+		lineTotal := float64(item.Quantity) * item.Price
+		subtotal += lineTotal
+		doc.Row([]pdf.Column{
+			{Text: item.ProductName, Width: widths[0]},
+			{Text: strconv.Itoa(item.Quantity), Width: widths[1], AlignRight: true},
+			{Text: fmt.Sprintf("$%.2f", item.Price), Width: widths[2], AlignRight: true},
+			{Text: fmt.Sprintf("$%.2f", lineTotal), Width: widths[3], AlignRight: true},
+		}, 10, false)
+	}
+	doc.Rule()
+
+	labelWidth := widths[0] + widths[1] + widths[2]
+	doc.Row([]pdf.Column{
+		{Text: "Subtotal", Width: labelWidth},
+		{Text: fmt.Sprintf("$%.2f", subtotal), Width: widths[3], AlignRight: true},
+	}, 10, false)
+	if order.Discount > 0 {
+		doc.Row([]pdf.Column{
+			{Text: fmt.Sprintf("Discount (%s)", order.PromoCode), Width: labelWidth},
+			{Text: fmt.Sprintf("-$%.2f", order.Discount), Width: widths[3], AlignRight: true},
+		}, 10, false)
+	}
+	doc.Row([]pdf.Column{
+		{Text: "Total", Width: labelWidth},
+		{Text: fmt.Sprintf("$%.2f", order.TotalPrice), Width: widths[3], AlignRight: true},
+	}, 12, true)
+
+	doc.Space(20)
+	doc.Text(fmt.Sprintf("Thank you for shopping with %s!", shopName), 10)
+
+	return doc.Bytes()
+}
@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"my-telegram-bot/pkg/api"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const (
+	// searchSyncInterval is how often the search index reloads the catalog
+	searchSyncInterval = 15 * time.Minute
+	// catalogSyncPerPage is the page size used to load the whole catalog into the search index
+	catalogSyncPerPage = 100
+	// maxCatalogSyncPages stops loading a catalog whose pages never end
+	maxCatalogSyncPages = 1000
+	// catalogServiceChatID keeps the service token set with SetCatalogToken among the user tokens.
+	// Telegram never uses 0 as a chat ID.
+	catalogServiceChatID int64 = 0
+)
+
+// SetCatalogToken sets the API token used to load the catalog into the search index.
+// Without one, the catalog is loaded with the credentials of the first user who searches.
+func (b *Bot) SetCatalogToken(token string) {
+	b.auth.SetToken(token, catalogServiceChatID)
+}
+
+// findProducts returns a page of the products matching the query.
+// Searches are answered from the local search index when it can apply all the filters of the query,
+// and from the backend otherwise, including while the first catalog is still loading.
+func (b *Bot) findProducts(chatID int64, query api.ProductQuery) ([]api.Product, bool, error) {
+	if query.Search != "" {
+		if b.searchIndex.Len() == 0 {
+			// Offers the user's credentials to the sync loop, which has none when the bot has no catalog token
+			select {
+			case b.catalogSyncs <- chatID:
+			default:
+			}
+		}
+		if products, hasNextPage, ok := b.searchLocally(query); ok {
+			return products, hasNextPage, nil
+		}
+	}
+	return b.apiClient.GetProducts(query, b.auth, chatID)
+}
+
+// keepSearchIndexSynced loads the catalog into the search index when the bot starts and reloads it every interval.
+// It uses the catalog token, or without one the credentials of the first user who searches,
+// and keeps them until they are gone. Searches keep using the old catalog while a new one is loading.
+func (b *Bot) keepSearchIndexSynced(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	chatID := catalogServiceChatID
+	b.syncSearchIndex(chatID)
+	for {
+		select {
+		case <-ticker.C:
+		case requested := <-b.catalogSyncs:
+			if b.auth.GetToken(chatID) != "" {
+				// A failed sync is retried on the next tick
+				continue
+			}
+			chatID = requested
+		}
+		b.syncSearchIndex(chatID)
+	}
+}
+
+// syncSearchIndex reloads the catalog into the search index with the credentials of the chat.
+// It does nothing when the chat has no token or another sync is running.
+func (b *Bot) syncSearchIndex(chatID int64) {
+	if b.auth.GetToken(chatID) == "" || !b.searchIndex.BeginSync(0) {
+		return
+	}
+	products, err := b.fetchCatalog(chatID)
+	if err != nil {
+		log.Printf("Error syncing search index: %v", err)
+	}
+	b.searchIndex.EndSync(products, err)
+}
+
+// fetchCatalog loads every product of the catalog page by page.
+func (b *Bot) fetchCatalog(chatID int64) ([]api.Product, error) {
+	var catalog []api.Product
+	for page := 1; page <= maxCatalogSyncPages; page++ {
+		products, hasNextPage, err := b.apiClient.ListProducts(api.ProductQuery{Page: page, PerPage: catalogSyncPerPage}, b.auth, chatID)
+		if err != nil {
+			return nil, err
+		}
+		catalog = append(catalog, products...)
+		if !hasNextPage {
+			return catalog, nil
+		}
+	}
+	return catalog, nil
+}
+
+// searchLocally finds a page of products in the search index, the most relevant first.
+// It reports false when the index is empty or the query filters or sorts on something the index does not know.
+func (b *Bot) searchLocally(query api.ProductQuery) ([]api.Product, bool, bool) {
+	if b.searchIndex.Len() == 0 || query.CategoryID > 0 || query.InStock {
+		return nil, false, false
+	}
+	// The index has no dates or sales, so only the price orders can replace the relevance
+	if query.Sort == api.SortNewest || query.Sort == api.SortPopularity {
+		return nil, false, false
+	}
+
+	var products []api.Product
+	for _, result := range b.searchIndex.Search(query.Search) {
+		product := result.Product
+		if (query.MinPrice > 0 && product.Price < query.MinPrice) || (query.MaxPrice > 0 && product.Price > query.MaxPrice) ||
+			(query.MinWeight > 0 && product.Weight < query.MinWeight) || (query.MaxWeight > 0 && product.Weight > query.MaxWeight) {
+			continue
+		}
+		products = append(products, product)
+	}
+
+	switch query.Sort {
+	case api.SortPriceAsc:
+		sort.SliceStable(products, func(i, j int) bool { return products[i].Price < products[j].Price })
+	case api.SortPriceDesc:
+		sort.SliceStable(products, func(i, j int) bool { return products[i].Price > products[j].Price })
+	}
+
+	start := (query.Page - 1) * query.PerPage
+	if start < 0 || start >= len(products) {
+		return nil, false, true
+	}
+	end := start + query.PerPage
+	if end > len(products) {
+		end = len(products)
+	}
+	return products[start:end], end < len(products), true
+}
+
+// searchSuggestion returns the search text with its typos corrected, or an empty string when it looks right.
+func (b *Bot) searchSuggestion(search string) string {
+	suggestion := b.searchIndex.Suggest(search)
+	if strings.EqualFold(suggestion, strings.TrimSpace(search)) {
+		return ""
+	}
+	return suggestion
+}
+
+// handleNoSearchResults tells the user nothing matched the search and offers the corrected search when there is one.
+func (b *Bot) handleNoSearchResults(chatID int64, query api.ProductQuery) {
+	suggestion := b.searchSuggestion(query.Search)
+	if suggestion == "" {
+		b.replyWithMessage(chatID, fmt.Sprintf("No products found for '%s'.", query.Search), nil)
+		return
+	}
+
+	text := fmt.Sprintf("No products found for '%s'. Did you mean '%s'?", query.Search, suggestion)
+	query.Search = suggestion
+	query.Page = 0
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔍 "+suggestion, fmt.Sprintf("search_for_%d", b.saveQuery(chatID, query))),
+	))
+	b.sendTextMessageWithReplyMarkup(chatID, text, keyboard)
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"encoding/json"
+	"my-telegram-bot/pkg/api"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFirstSearchDoesNotWaitForCatalog(t *testing.T) {
+	b, _, backend := newTestBot(t)
+
+	backend.handleJSON("GET /cart", http.StatusOK, api.CartResponse{})
+	backend.handleJSON("GET /products", http.StatusOK, api.ProductsResponse{Data: []api.Product{{ID: 1, Name: "Goat cheese"}}})
+
+	products, _, err := b.findProducts(testChatID, api.ProductQuery{Page: 1, PerPage: perPage, Search: "cheese"})
+	if err != nil {
+		t.Fatalf("findProducts: %v", err)
+	}
+	if len(products) != 1 || products[0].Name != "Goat cheese" {
+		t.Errorf("findProducts = %+v, want the backend results", products)
+	}
+	if got := len(backend.received("GET /products")); got != 1 {
+		t.Errorf("backend received %d product requests, want only the search", got)
+	}
+
+	select {
+	case chatID := <-b.catalogSyncs:
+		if chatID != testChatID {
+			t.Errorf("sync loop offered the credentials of chat %d, want %d", chatID, testChatID)
+		}
+	default:
+		t.Error("the search did not offer its credentials to the sync loop")
+	}
+}
+
+func TestSyncSearchIndexUsesCatalogToken(t *testing.T) {
+	b, _, backend := newTestBot(t)
+	b.SetCatalogToken("catalog-token")
+
+	var authorizations []string
+	backend.handle("GET /products", func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(api.ProductsResponse{Data: []api.Product{{ID: 1, Name: "Goat cheese"}}})
+	})
+
+	b.syncSearchIndex(testChatID + 1)
+	if len(authorizations) != 0 {
+		t.Fatalf("synced with a chat that has no token: %v", authorizations)
+	}
+
+	b.syncSearchIndex(catalogServiceChatID)
+	if len(authorizations) != 1 || authorizations[0] != "Bearer catalog-token" {
+		t.Errorf("catalog requested with %v, want the catalog token", authorizations)
+	}
+	if got := b.searchIndex.Len(); got != 1 {
+		t.Errorf("search index has %d products, want 1", got)
+	}
+}
+
+func TestSyncLoopUsesFirstSearcherWithoutCatalogToken(t *testing.T) {
+	b, _, backend := newTestBot(t)
+
+	synced := make(chan string, 1)
+	backend.handle("GET /products", func(w http.ResponseWriter, r *http.Request) {
+		synced <- r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(api.ProductsResponse{Data: []api.Product{{ID: 1, Name: "Goat cheese"}}})
+	})
+
+	b.catalogSyncs <- testChatID
+	go b.keepSearchIndexSynced(time.Hour)
+
+	select {
+	case authorization := <-synced:
+		if authorization != "Bearer test-token" {
+			t.Errorf("catalog requested with %q, want the token of the user who searched", authorization)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the sync loop did not load the catalog")
+	}
+}
+
+func TestSearchLocallyOnlyForKnownOrders(t *testing.T) {
+	b, _, _ := newTestBot(t)
+	b.searchIndex.Load([]api.Product{{ID: 1, Name: "Goat cheese", Price: 5}, {ID: 2, Name: "Blue cheese", Price: 3}})
+
+	tests := []struct {
+		sort  string
+		local bool
+	}{
+		{"", true},
+		{api.SortPriceAsc, true},
+		{api.SortPriceDesc, true},
+		{api.SortNewest, false},
+		{api.SortPopularity, false},
+	}
+	for _, tt := range tests {
+		_, _, ok := b.searchLocally(api.ProductQuery{Page: 1, PerPage: perPage, Search: "cheese", Sort: tt.sort})
+		if ok != tt.local {
+			t.Errorf("searchLocally with sort %q = %v, want %v", tt.sort, ok, tt.local)
+		}
+	}
+}
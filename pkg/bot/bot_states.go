@@ -2,6 +2,7 @@ package bot
 
 import (
 	"my-telegram-bot/pkg/api"
+	"my-telegram-bot/pkg/geo"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
@@ -111,6 +112,58 @@ func (b *Bot) initUserState(chatID int64, contact *tgbotapi.Contact) {
 	}
 }
 
+// setPendingAddress sets the address being edited in the address book for the given chatID.
+func (b *Bot) setPendingAddress(chatID int64, address api.SavedAddress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.states[chatID].PendingAddress = address
+}
+
+// getPendingAddress retrieves the address being edited in the address book for the given chatID.
+func (b *Bot) getPendingAddress(chatID int64) api.SavedAddress {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.states[chatID].PendingAddress
+}
+
+// setPendingProduct sets the product whose quantity is being typed for the given chatID.
+func (b *Bot) setPendingProduct(chatID int64, productID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.states[chatID].ProductID = productID
+}
+
+// getPendingProduct retrieves the product whose quantity is being typed for the given chatID.
+func (b *Bot) getPendingProduct(chatID int64) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.states[chatID].ProductID
+}
+
+// setPendingLocation sets the address resolved from a shared location and the step that asked for it.
+func (b *Bot) setPendingLocation(chatID int64, address geo.Address, flow string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.states[chatID]
+	state.Location = address
+	state.LocationFlow = flow
+}
+
+// getPendingLocation retrieves the address resolved from a shared location and the step that asked for it.
+func (b *Bot) getPendingLocation(chatID int64) (geo.Address, string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	state := b.states[chatID]
+	return state.Location, state.LocationFlow
+}
+
+// setAddressDetails sets the structured registration address for the given chatID.
+func (b *Bot) setAddressDetails(chatID int64, address *geo.Address) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.states[chatID].Data.AddressDetails = address
+}
+
 // setDataForImageState sets the image data for the state of a given chatID.
 func (b *Bot) setDataForImageState(chatID int64, handler func(*UserState, []byte), value []byte) {
 	b.mu.Lock()
@@ -0,0 +1,187 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"my-telegram-bot/pkg/api"
+	"my-telegram-bot/pkg/auth"
+	"my-telegram-bot/pkg/store"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// testChatID is the chat of the user in the recorded updates under testdata.
+const testChatID int64 = 1001
+
+// telegramRequest is a Bot API call made by the bot.
+type telegramRequest struct {
+	Method string
+	Params url.Values
+}
+
+// fakeTelegram replaces the transport of the Bot API client. It records every call
+// and answers it with a message, which is what most Bot API methods return.
+type fakeTelegram struct {
+	mu       sync.Mutex
+	requests []telegramRequest
+}
+
+func (t *fakeTelegram) RoundTrip(req *http.Request) (*http.Response, error) {
+	params := url.Values{}
+	contentType := req.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "multipart/form-data"):
+		if err := req.ParseMultipartForm(32 << 20); err == nil {
+			params = req.MultipartForm.Value
+		}
+	case req.Body != nil:
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+	}
+
+	t.mu.Lock()
+	t.requests = append(t.requests, telegramRequest{Method: path.Base(req.URL.Path), Params: params})
+	messageID := len(t.requests) + 100
+	t.mu.Unlock()
+
+	chatID := params.Get("chat_id")
+	if chatID == "" {
+		chatID = "0"
+	}
+	body := fmt.Sprintf(`{"ok":true,"result":{"message_id":%d,"date":0,"chat":{"id":%s,"type":"private"}}}`, messageID, chatID)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// calls returns the parameters of the calls of the given Bot API method.
+func (t *fakeTelegram) calls(method string) []url.Values {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var calls []url.Values
+	for _, request := range t.requests {
+		if request.Method == method {
+			calls = append(calls, request.Params)
+		}
+	}
+	return calls
+}
+
+// texts returns the texts of the messages sent and edited by the bot.
+func (t *fakeTelegram) texts() []string {
+	var texts []string
+	for _, method := range []string{"sendMessage", "editMessageText"} {
+		for _, params := range t.calls(method) {
+			texts = append(texts, params.Get("text"))
+		}
+	}
+	return texts
+}
+
+// backendRequest is a request received by the fake backend.
+type backendRequest struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// fakeBackend serves the backend API from handlers registered by method and path.
+// Requests without a handler get 404, like routes a backend does not implement.
+type fakeBackend struct {
+	mu       sync.Mutex
+	handlers map[string]http.HandlerFunc
+	requests []backendRequest
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{handlers: make(map[string]http.HandlerFunc)}
+}
+
+// handle registers a handler for requests like "GET /cart".
+func (f *fakeBackend) handle(route string, handler http.HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[route] = handler
+}
+
+// handleJSON registers a handler that answers with the given status and JSON body.
+func (f *fakeBackend) handleJSON(route string, status int, body interface{}) {
+	f.handle(route, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	})
+}
+
+func (f *fakeBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(strings.NewReader(string(body)))
+
+	f.mu.Lock()
+	f.requests = append(f.requests, backendRequest{Method: r.Method, Path: r.URL.Path, Body: body})
+	handler := f.handlers[r.Method+" "+r.URL.Path]
+	f.mu.Unlock()
+
+	if handler == nil {
+		http.NotFound(w, r)
+		return
+	}
+	handler(w, r)
+}
+
+// received returns the requests received for the given route.
+func (f *fakeBackend) received(route string) []backendRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var requests []backendRequest
+	for _, request := range f.requests {
+		if request.Method+" "+request.Path == route {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// newTestBot creates a bot for the test user that talks to a fake Telegram and a fake backend.
+func newTestBot(t *testing.T) (*Bot, *fakeTelegram, *fakeBackend) {
+	t.Helper()
+
+	backend := newFakeBackend()
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	telegram := &fakeTelegram{}
+	botAPI := &tgbotapi.BotAPI{Token: "test", Client: &http.Client{Transport: telegram}}
+	authClient := &auth.AuthClient{Tokens: map[int64]string{testChatID: "test-token"}}
+	apiClient := api.NewAPIClient(server.URL)
+
+	return newBot(botAPI, apiClient, authClient, store.NewFileStore(t.TempDir()), nil), telegram, backend
+}
+
+// loadUpdate reads a recorded update from testdata.
+func loadUpdate(t *testing.T, name string) tgbotapi.Update {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading %s: %v", name, err)
+	}
+	var update tgbotapi.Update
+	if err := json.Unmarshal(data, &update); err != nil {
+		t.Fatalf("decoding %s: %v", name, err)
+	}
+	return update
+}
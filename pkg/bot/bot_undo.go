@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// undoWindow is how long a removal from the cart can be undone
+const undoWindow = 30 * time.Second
+
+// RemovedCartItems stores the quantities of the products removed from the cart until the removal can no longer be undone
+type RemovedCartItems struct {
+	Quantities map[int]int
+	MessageID  int
+	ExpiresAt  time.Time
+}
+
+// offerUndo sends text with an "Undo" button that restores the removed quantities for a short time.
+// A new removal replaces the previous one, whose button stops working.
+func (b *Bot) offerUndo(chatID int64, quantities map[int]int, text string) {
+	// Products whose previous quantity is unknown cannot be restored
+	for productID, quantity := range quantities {
+		if quantity <= 0 {
+			delete(quantities, productID)
+		}
+	}
+	if len(quantities) == 0 {
+		b.replyWithMessage(chatID, text, nil)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("↩️ Undo (%ds)", int(undoWindow.Seconds())), "undo_removal"),
+	))
+	sentMsg, err := b.bot.Send(msg)
+	if err != nil {
+		log.Printf("Error sending undo message: %v", err)
+		return
+	}
+
+	b.removedItems[chatID] = &RemovedCartItems{
+		Quantities: quantities,
+		MessageID:  sentMsg.MessageID,
+		ExpiresAt:  time.Now().Add(undoWindow),
+	}
+
+	// Hide the button once the removal can no longer be undone
+	time.AfterFunc(undoWindow, func() {
+		edit := tgbotapi.NewEditMessageReplyMarkup(chatID, sentMsg.MessageID, tgbotapi.InlineKeyboardMarkup{
+			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+		})
+		if _, err := b.bot.Send(edit); err != nil {
+			log.Printf("Error hiding undo button: %v", err)
+		}
+	})
+}
+
+// handleUndoRemoval puts the removed products back into the cart with their previous quantities.
+// The quantities are set rather than added, so products added again since the removal are not doubled.
+func (b *Bot) handleUndoRemoval(chatID int64, messageID int) {
+	removed, ok := b.removedItems[chatID]
+	if !ok || removed.MessageID != messageID || time.Now().After(removed.ExpiresAt) {
+		b.replyWithMessage(chatID, "It's too late to undo this removal.", nil)
+		return
+	}
+	delete(b.removedItems, chatID)
+
+	restored := 0
+	for productID, quantity := range removed.Quantities {
+		if err := b.setCartQuantity(chatID, productID, quantity); err != nil {
+			log.Printf("Error restoring product %d: %v", productID, err)
+			continue
+		}
+		restored++
+	}
+
+	text := "Removal undone ↩️ Your cart is restored."
+	if restored < len(removed.Quantities) {
+		text = "Some products could not be restored. Please check your cart."
+	}
+	if _, err := b.bot.Send(tgbotapi.NewEditMessageText(chatID, messageID, text)); err != nil {
+		log.Printf("Error closing undo message: %v", err)
+	}
+}
+
+// handleClearCartAction asks to confirm clearing the cart from the cart summary, and clears it once confirmed.
+func (b *Bot) handleClearCartAction(chatID int64, messageID int, data string) {
+	switch data {
+	case "clear_cart":
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Yes, clear it", "clear_cart_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ No, keep it", "clear_cart_cancel"),
+		))
+		if err := b.editMessageWithReplyMarkup(chatID, messageID, "Remove all products from your cart?", "", keyboard); err != nil {
+			log.Printf("Error asking to clear the cart: %v", err)
+		}
+	case "clear_cart_cancel":
+		b.renderCartSummary(chatID, messageID)
+	case "clear_cart_confirm":
+		b.clearCart(chatID)
+		b.renderCartSummary(chatID, messageID)
+	}
+}
+
+// clearCart removes every product from the cart and offers to undo it.
+func (b *Bot) clearCart(chatID int64) {
+	cartItems, err := b.apiClient.GetCartItems(b.auth, true, chatID)
+	if err != nil {
+		b.replyWithMessage(chatID, "An error occurred while fetching your cart. Please try again.", nil)
+		return
+	}
+
+	removed := make(map[int]int)
+	for _, item := range cartItems {
+		if err := b.reduceOrIncreaseAmountInCart(chatID, item.ProductID, 0, true); err != nil {
+			log.Printf("Error removing product %d: %v", item.ProductID, err)
+			continue
+		}
+		removed[item.ProductID] = item.Quantity
+		if cartItem := b.cart[chatID][item.ProductID]; cartItem.MessageID != 0 {
+			if err := b.editCartMessage(chatID, cartItem.MessageID, item.ProductID); err != nil {
+				log.Printf("Error refreshing product card: %v", err)
+			}
+		}
+	}
+
+	b.offerUndo(chatID, removed, "Your cart is cleared 🗑")
+}
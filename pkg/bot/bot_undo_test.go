@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestUndoRestoresPreviousQuantity(t *testing.T) {
+	b, telegram, backend := newTestBot(t)
+	backend.handleJSON("PUT /cart/5", http.StatusOK, map[string]string{})
+
+	b.offerUndo(testChatID, map[int]int{5: 3}, "Product is removed")
+	removed := b.removedItems[testChatID]
+	if removed == nil {
+		t.Fatal("the removal cannot be undone")
+	}
+	// The product is added again before the removal is undone
+	b.cart[testChatID] = map[int]BotCartItem{5: {Quantity: 1}}
+
+	b.handleUndoRemoval(testChatID, removed.MessageID)
+
+	puts := backend.received("PUT /cart/5")
+	if len(puts) != 1 || string(puts[0].Body) != `{"quantity":3}` {
+		t.Fatalf("backend received %q, want the quantity set back to 3", puts)
+	}
+	if got := len(backend.received("POST /cart")); got != 0 {
+		t.Errorf("backend received %d additions, want the quantity set", got)
+	}
+	if got := b.cart[testChatID][5].Quantity; got != 3 {
+		t.Errorf("cart quantity = %d, want 3", got)
+	}
+	if !containsText(telegram.texts(), "Removal undone") {
+		t.Errorf("replies %q do not confirm the undo", telegram.texts())
+	}
+}
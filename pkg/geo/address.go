@@ -0,0 +1,46 @@
+package geo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Address is a structured delivery address that a courier can use.
+type Address struct {
+	Street    string  `json:"street"`
+	Building  string  `json:"building"`
+	Apartment string  `json:"apartment,omitempty"`
+	City      string  `json:"city"`
+	Postcode  string  `json:"postcode,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+}
+
+// HasCoordinates reports whether the address carries the location it was resolved from.
+func (a Address) HasCoordinates() bool {
+	return a.Latitude != 0 || a.Longitude != 0
+}
+
+// IsComplete reports whether the address has the details required for a delivery.
+func (a Address) IsComplete() bool {
+	return a.Street != "" && a.Building != "" && a.City != ""
+}
+
+// String formats the address on a single line, e.g. "Main Street 5, apt. 12, 10115 Berlin".
+// An address without a street falls back to its coordinates.
+func (a Address) String() string {
+	var parts []string
+	if line := strings.TrimSpace(a.Street + " " + a.Building); line != "" {
+		parts = append(parts, line)
+	}
+	if a.Apartment != "" {
+		parts = append(parts, "apt. "+a.Apartment)
+	}
+	if line := strings.TrimSpace(a.Postcode + " " + a.City); line != "" {
+		parts = append(parts, line)
+	}
+	if len(parts) == 0 && a.HasCoordinates() {
+		return fmt.Sprintf("%.6f, %.6f", a.Latitude, a.Longitude)
+	}
+	return strings.Join(parts, ", ")
+}
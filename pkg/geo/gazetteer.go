@@ -0,0 +1,230 @@
+package geo
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// gazetteerColumns lists the columns expected in a gazetteer file, in order
+var gazetteerColumns = []string{"street", "building", "postcode", "city", "latitude", "longitude"}
+
+// DefaultMaxDistance is how far, in meters, a location may be from the nearest known building to still match it
+const DefaultMaxDistance = 150.0
+
+//go:embed gazetteer.csv
+var defaultGazetteer string
+
+// Gazetteer is an offline Geocoder backed by a list of known building addresses.
+// A location is resolved to the nearest building within MaxDistance.
+type Gazetteer struct {
+	entries     []Address
+	MaxDistance float64
+}
+
+// DefaultGazetteer creates a gazetteer from the sample data bundled with the bot.
+func DefaultGazetteer() (*Gazetteer, error) {
+	return NewGazetteer(strings.NewReader(defaultGazetteer))
+}
+
+// LoadGazetteer creates a gazetteer from a CSV file.
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening gazetteer: %w", err)
+	}
+	defer f.Close()
+
+	return NewGazetteer(f)
+}
+
+// NewGazetteer reads gazetteer entries from CSV with the header
+// street,building,postcode,city,latitude,longitude.
+func NewGazetteer(r io.Reader) (*Gazetteer, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(gazetteerColumns)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading gazetteer header: %w", err)
+	}
+	for i, column := range gazetteerColumns {
+		if strings.ToLower(header[i]) != column {
+			return nil, fmt.Errorf("unexpected gazetteer column %q, want %q", header[i], column)
+		}
+	}
+
+	g := &Gazetteer{MaxDistance: DefaultMaxDistance}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading gazetteer: %w", err)
+		}
+
+		latitude, err := strconv.ParseFloat(record[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude %q: %w", record[4], err)
+		}
+		longitude, err := strconv.ParseFloat(record[5], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude %q: %w", record[5], err)
+		}
+
+		g.entries = append(g.entries, Address{
+			Street:    record[0],
+			Building:  record[1],
+			Postcode:  record[2],
+			City:      record[3],
+			Latitude:  latitude,
+			Longitude: longitude,
+		})
+	}
+
+	return g, nil
+}
+
+// Reverse returns the address of the building nearest to the location.
+// The returned address keeps the coordinates of the location rather than those of the building.
+func (g *Gazetteer) Reverse(latitude, longitude float64) (*Address, error) {
+	var nearest *Address
+	nearestDistance := g.MaxDistance
+	for i := range g.entries {
+		distance := Distance(latitude, longitude, g.entries[i].Latitude, g.entries[i].Longitude)
+		if distance <= nearestDistance {
+			nearest = &g.entries[i]
+			nearestDistance = distance
+		}
+	}
+	if nearest == nil {
+		return nil, ErrNoMatch
+	}
+
+	address := *nearest
+	address.Latitude = latitude
+	address.Longitude = longitude
+	return &address, nil
+}
+
+// Forward finds the building of an address typed by the user, such as "Torstraße 101, Berlin".
+// The street must be known and followed or preceded by a building number. A number missing from
+// the gazetteer is placed at the building of the same street with the closest number.
+// When the query names one of the known cities, only buildings in that city match.
+func (g *Gazetteer) Forward(query string) (*Address, error) {
+	words := strings.Fields(normalizeAddress(query))
+
+	city := ""
+	for _, entry := range g.entries {
+		if indexWords(words, strings.Fields(normalizeAddress(entry.City))) >= 0 {
+			city = entry.City
+			break
+		}
+	}
+
+	var best *Address
+	bestDistance := 0
+	for i := range g.entries {
+		entry := &g.entries[i]
+		if city != "" && entry.City != city {
+			continue
+		}
+		building, ok := buildingNumber(words, strings.Fields(normalizeAddress(entry.Street)))
+		if !ok {
+			continue
+		}
+		if building == normalizeAddress(entry.Building) {
+			best = entry
+			break
+		}
+
+		number, ok := leadingNumber(building)
+		known, knownOK := leadingNumber(entry.Building)
+		if !ok || !knownOK {
+			continue
+		}
+		distance := number - known
+		if distance < 0 {
+			distance = -distance
+		}
+		if best == nil || distance < bestDistance {
+			best = entry
+			bestDistance = distance
+		}
+	}
+	if best == nil {
+		return nil, ErrNoMatch
+	}
+
+	address := *best
+	return &address, nil
+}
+
+// buildingNumber finds the street in the words of a query and returns the building number
+// written after it, or before it as in "5 Main Street".
+func buildingNumber(words, street []string) (string, bool) {
+	i := indexWords(words, street)
+	if i < 0 {
+		return "", false
+	}
+	if next := i + len(street); next < len(words) && isDigit(words[next][0]) {
+		return words[next], true
+	}
+	if i > 0 && isDigit(words[i-1][0]) {
+		return words[i-1], true
+	}
+	return "", false
+}
+
+// indexWords returns the position of the phrase in the words, or -1 if the phrase is not there.
+func indexWords(words, phrase []string) int {
+	if len(phrase) == 0 {
+		return -1
+	}
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		if strings.Join(words[i:i+len(phrase)], " ") == strings.Join(phrase, " ") {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeAddress lowercases an address, keeps only letters and digits separated by single spaces
+// and spells out the common abbreviation of "Straße".
+func normalizeAddress(text string) string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ß", "ss")
+
+	var words []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if strings.HasSuffix(word, "str") {
+			word += "asse"
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " ")
+}
+
+// leadingNumber parses the number at the start of a building number such as "5a".
+func leadingNumber(building string) (int, bool) {
+	end := 0
+	for end < len(building) && isDigit(building[end]) {
+		end++
+	}
+	number, err := strconv.Atoi(building[:end])
+	return number, err == nil
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,211 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+const testGazetteer = `street,building,postcode,city,latitude,longitude
+Main Street,1,10115,Berlin,52.520000,13.400000
+Main Street,3,10115,Berlin,52.520000,13.401000
+Side Street,10,10117,Berlin,52.525000,13.400000
+`
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 52.52, 13.4, 52.52, 13.4, 0},
+		{"one degree of latitude", 0, 0, 1, 0, 111195},
+		{"one degree of longitude at the equator", 0, 0, 0, 1, 111195},
+		{"one degree of longitude at 60 degrees", 60, 0, 60, 1, 55596},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, 111195},
+		{"antipodes", 0, 0, 0, 180, math.Pi * earthRadius},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.want) > 1 {
+				t.Errorf("Distance = %.1f, want %.1f", got, tt.want)
+			}
+			if back := Distance(tt.lat2, tt.lon2, tt.lat1, tt.lon1); math.Abs(back-got) > 1e-6 {
+				t.Errorf("Distance is not symmetric: %.3f and %.3f", got, back)
+			}
+		})
+	}
+}
+
+func TestGazetteerReverse(t *testing.T) {
+	g, err := NewGazetteer(strings.NewReader(testGazetteer))
+	if err != nil {
+		t.Fatalf("NewGazetteer: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		lat, lon     float64
+		wantStreet   string
+		wantBuilding string
+		wantErr      error
+	}{
+		{"exact building", 52.52, 13.4, "Main Street", "1", nil},
+		{"closer to the second building", 52.52, 13.4007, "Main Street", "3", nil},
+		{"other street", 52.5249, 13.4001, "Side Street", "10", nil},
+		// 0.0013 degrees of latitude are about 145 meters, still within DefaultMaxDistance
+		{"near the edge of the range", 52.5213, 13.4, "Main Street", "1", nil},
+		// About 167 meters from the nearest building
+		{"out of range", 52.5215, 13.4, "", "", ErrNoMatch},
+		{"far away", 48.8566, 2.3522, "", "", ErrNoMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, err := g.Reverse(tt.lat, tt.lon)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Reverse error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reverse: %v", err)
+			}
+			if address.Street != tt.wantStreet || address.Building != tt.wantBuilding {
+				t.Errorf("Reverse = %s %s, want %s %s", address.Street, address.Building, tt.wantStreet, tt.wantBuilding)
+			}
+			if address.Latitude != tt.lat || address.Longitude != tt.lon {
+				t.Errorf("Reverse kept %.6f, %.6f, want the shared location %.6f, %.6f", address.Latitude, address.Longitude, tt.lat, tt.lon)
+			}
+			if address.City != "Berlin" || !address.IsComplete() {
+				t.Errorf("Reverse = %+v, want a complete address", address)
+			}
+		})
+	}
+}
+
+func TestGazetteerMaxDistance(t *testing.T) {
+	g, err := NewGazetteer(strings.NewReader(testGazetteer))
+	if err != nil {
+		t.Fatalf("NewGazetteer: %v", err)
+	}
+	g.MaxDistance = 50
+
+	if _, err := g.Reverse(52.5213, 13.4); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("Reverse error = %v, want ErrNoMatch with a smaller range", err)
+	}
+}
+
+func TestNewGazetteerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"empty", ""},
+		{"wrong header", "street,number,postcode,city,latitude,longitude\n"},
+		{"missing column", "street,building,postcode,city,latitude\nMain Street,1,10115,Berlin,52.52\n"},
+		{"invalid latitude", testGazetteer + "Main Street,5,10115,Berlin,north,13.4\n"},
+		{"invalid longitude", testGazetteer + "Main Street,5,10115,Berlin,52.52,east\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewGazetteer(strings.NewReader(tt.csv)); err == nil {
+				t.Error("NewGazetteer succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestDefaultGazetteer(t *testing.T) {
+	g, err := DefaultGazetteer()
+	if err != nil {
+		t.Fatalf("DefaultGazetteer: %v", err)
+	}
+	address, err := g.Reverse(52.517036, 13.397634)
+	if err != nil {
+		t.Fatalf("Reverse: %v", err)
+	}
+	if address.String() != "Unter den Linden 1, 10117 Berlin" {
+		t.Errorf("Reverse = %q", address.String())
+	}
+}
+
+func TestGazetteerForward(t *testing.T) {
+	g, err := DefaultGazetteer()
+	if err != nil {
+		t.Fatalf("DefaultGazetteer: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"street and number", "Torstraße 101", "Torstraße 101, 10119 Berlin"},
+		{"with postcode and city", "Torstraße 101, 10119 Berlin", "Torstraße 101, 10119 Berlin"},
+		{"different case and punctuation", "  torstrasse,101 ", "Torstraße 101, 10119 Berlin"},
+		{"abbreviated street", "Friedrichstr. 43", "Friedrichstraße 43, 10117 Berlin"},
+		{"abbreviated separate word", "Leipziger Str. 65", "Leipziger Straße 65, 10117 Berlin"},
+		{"number before the street", "1 Potsdamer Platz", "Potsdamer Platz 1, 10785 Berlin"},
+		{"street of several words", "Unter den Linden 40", "Unter den Linden 40, 10117 Berlin"},
+		{"apartment after the number", "Unter den Linden 21, apt. 1", "Unter den Linden 21, 10117 Berlin"},
+		{"unknown number takes the closest one", "Friedrichstraße 80", "Friedrichstraße 76, 10117 Berlin"},
+		{"building with a letter", "Kurfürstendamm 21a", "Kurfürstendamm 21, 10719 Berlin"},
+		{"unknown street", "Baker Street 221b", ""},
+		{"street without a number", "Torstraße", ""},
+		{"number that is not next to the street", "Torstraße, Berlin 10119", ""},
+		{"city missing from the gazetteer", "Torstraße 101, Hamburg", "Torstraße 101, 10119 Berlin"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, err := g.Forward(tt.query)
+			if tt.want == "" {
+				if !errors.Is(err, ErrNoMatch) {
+					t.Fatalf("Forward(%q) = %v, %v, want ErrNoMatch", tt.query, address, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Forward(%q): %v", tt.query, err)
+			}
+			if address.String() != tt.want {
+				t.Errorf("Forward(%q) = %q, want %q", tt.query, address.String(), tt.want)
+			}
+			if !address.HasCoordinates() {
+				t.Errorf("Forward(%q) has no coordinates", tt.query)
+			}
+		})
+	}
+}
+
+func TestGazetteerForwardCity(t *testing.T) {
+	g, err := NewGazetteer(strings.NewReader(testGazetteer + "Main Street,1,20095,Hamburg,53.550000,10.000000\n"))
+	if err != nil {
+		t.Fatalf("NewGazetteer: %v", err)
+	}
+
+	tests := []struct {
+		query    string
+		wantCity string
+	}{
+		{"Main Street 1, Hamburg", "Hamburg"},
+		{"Main Street 1, Berlin", "Berlin"},
+		{"Main Street 1", "Berlin"},
+	}
+	for _, tt := range tests {
+		address, err := g.Forward(tt.query)
+		if err != nil {
+			t.Fatalf("Forward(%q): %v", tt.query, err)
+		}
+		if address.City != tt.wantCity {
+			t.Errorf("Forward(%q) city = %q, want %q", tt.query, address.City, tt.wantCity)
+		}
+	}
+}
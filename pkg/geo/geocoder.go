@@ -0,0 +1,30 @@
+package geo
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNoMatch is returned when a geocoder finds no address for a location, or no location for an address.
+var ErrNoMatch = errors.New("no address found for the location")
+
+// earthRadius is the mean radius of the Earth in meters
+const earthRadius = 6371000.0
+
+// Geocoder resolves coordinates into a structured address and typed addresses into coordinates.
+type Geocoder interface {
+	Reverse(latitude, longitude float64) (*Address, error)
+	// Forward returns the structured address, with its coordinates, of an address typed by the user
+	Forward(query string) (*Address, error)
+}
+
+// Distance returns the great-circle distance in meters between two points.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+
+	h := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
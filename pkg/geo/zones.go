@@ -0,0 +1,176 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Zone is a named delivery area made of one or more polygons.
+// Each polygon is a list of rings of [longitude, latitude] points, as in GeoJSON:
+// the first ring is the outline and the following ones are holes.
+type Zone struct {
+	Name     string
+	Polygons [][][][2]float64
+}
+
+// Zones is the set of areas the shop delivers to.
+type Zones struct {
+	zones []Zone
+}
+
+// geoJSON mirrors the parts of a GeoJSON FeatureCollection used to describe the zones
+type geoJSON struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Properties struct {
+			Name string `json:"name"`
+		} `json:"properties"`
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// LoadZones reads the delivery zones from a GeoJSON file.
+func LoadZones(path string) (*Zones, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading delivery zones: %w", err)
+	}
+	return ParseZones(data)
+}
+
+// ParseZones reads the delivery zones from a GeoJSON FeatureCollection of Polygon and MultiPolygon features.
+// The name of each zone is taken from the "name" property of its feature.
+func ParseZones(data []byte) (*Zones, error) {
+	var collection geoJSON
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("decoding delivery zones: %w", err)
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("unexpected GeoJSON type %q, want FeatureCollection", collection.Type)
+	}
+
+	zones := &Zones{}
+	for i, feature := range collection.Features {
+		zone := Zone{Name: feature.Properties.Name}
+		if zone.Name == "" {
+			zone.Name = fmt.Sprintf("Zone %d", i+1)
+		}
+
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var polygon [][][2]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &polygon); err != nil {
+				return nil, fmt.Errorf("decoding zone %q: %w", zone.Name, err)
+			}
+			zone.Polygons = append(zone.Polygons, polygon)
+		case "MultiPolygon":
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &zone.Polygons); err != nil {
+				return nil, fmt.Errorf("decoding zone %q: %w", zone.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("zone %q has unsupported geometry %q", zone.Name, feature.Geometry.Type)
+		}
+
+		zones.zones = append(zones.zones, zone)
+	}
+
+	return zones, nil
+}
+
+// Find returns the zone containing the point, or nil if the point is outside all zones.
+func (z *Zones) Find(latitude, longitude float64) *Zone {
+	for i := range z.zones {
+		if z.zones[i].Contains(latitude, longitude) {
+			return &z.zones[i]
+		}
+	}
+	return nil
+}
+
+// Nearest returns the zone closest to the point together with the distance to its border in meters.
+// It returns nil when there are no zones.
+func (z *Zones) Nearest(latitude, longitude float64) (*Zone, float64) {
+	var nearest *Zone
+	nearestDistance := math.Inf(1)
+	for i := range z.zones {
+		if distance := z.zones[i].DistanceTo(latitude, longitude); distance < nearestDistance {
+			nearest = &z.zones[i]
+			nearestDistance = distance
+		}
+	}
+	return nearest, nearestDistance
+}
+
+// Contains reports whether the point lies inside the zone.
+func (zone *Zone) Contains(latitude, longitude float64) bool {
+	for _, polygon := range zone.Polygons {
+		if len(polygon) == 0 || !ringContains(polygon[0], latitude, longitude) {
+			continue
+		}
+		inHole := false
+		for _, hole := range polygon[1:] {
+			if ringContains(hole, latitude, longitude) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// DistanceTo returns the distance in meters from the point to the border of the zone, or 0 if the point is inside.
+func (zone *Zone) DistanceTo(latitude, longitude float64) float64 {
+	if zone.Contains(latitude, longitude) {
+		return 0
+	}
+
+	// Project the points on a plane around the given point, which is accurate enough at city scale
+	scaleY := earthRadius * math.Pi / 180
+	scaleX := scaleY * math.Cos(latitude*math.Pi/180)
+	project := func(point [2]float64) (float64, float64) {
+		return (point[0] - longitude) * scaleX, (point[1] - latitude) * scaleY
+	}
+
+	nearest := math.Inf(1)
+	for _, polygon := range zone.Polygons {
+		for _, ring := range polygon {
+			for i := 0; i+1 < len(ring); i++ {
+				x1, y1 := project(ring[i])
+				x2, y2 := project(ring[i+1])
+				nearest = math.Min(nearest, distanceToSegment(x1, y1, x2, y2))
+			}
+		}
+	}
+	return nearest
+}
+
+// ringContains reports whether the point lies inside the ring, using the even-odd rule.
+func ringContains(ring [][2]float64, latitude, longitude float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > latitude) != (yj > latitude) && longitude < (xj-xi)*(latitude-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// distanceToSegment returns the distance from the origin to the segment between two points on a plane.
+func distanceToSegment(x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, -(x1*dx+y1*dy)/length))
+	}
+	return math.Hypot(x1+t*dx, y1+t*dy)
+}
@@ -0,0 +1,190 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+// square returns a closed ring around the given corners, in GeoJSON [longitude, latitude] order.
+func square(minLon, minLat, maxLon, maxLat float64) [][2]float64 {
+	return [][2]float64{{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat}}
+}
+
+func TestRingContains(t *testing.T) {
+	ring := square(0, 0, 10, 10)
+	triangle := [][2]float64{{0, 0}, {10, 0}, {5, 10}, {0, 0}}
+
+	tests := []struct {
+		name     string
+		ring     [][2]float64
+		lat, lon float64
+		want     bool
+	}{
+		{"center", ring, 5, 5, true},
+		{"outside to the left", ring, 5, -1, false},
+		{"outside above", ring, 11, 5, false},
+		{"just inside a corner", ring, 0.001, 0.001, true},
+		{"just outside a corner", ring, -0.001, -0.001, false},
+		// Points on the border belong to one side only, so neighbouring zones never both claim them
+		{"on the left edge", ring, 5, 0, true},
+		{"on the right edge", ring, 5, 10, false},
+		{"on the bottom edge", ring, 0, 5, true},
+		{"on the top edge", ring, 10, 5, false},
+		{"inside the triangle", triangle, 2, 5, true},
+		{"beside the triangle slope", triangle, 8, 2, false},
+		{"level with the apex", triangle, 10, 5, false},
+		{"empty ring", nil, 5, 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ringContains(tt.ring, tt.lat, tt.lon); got != tt.want {
+				t.Errorf("ringContains(%v, %v) = %v, want %v", tt.lat, tt.lon, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRingContainsSharedEdge(t *testing.T) {
+	west := square(0, 0, 10, 10)
+	east := square(10, 0, 20, 10)
+
+	for _, lat := range []float64{0, 2.5, 5, 9.99} {
+		inWest, inEast := ringContains(west, lat, 10), ringContains(east, lat, 10)
+		if inWest == inEast {
+			t.Errorf("point %v on the shared edge: west %v, east %v, want exactly one", lat, inWest, inEast)
+		}
+	}
+}
+
+const testZones = `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "properties": {"name": "Center"},
+      "geometry": {
+        "type": "Polygon",
+        "coordinates": [
+          [[13.30, 52.45], [13.50, 52.45], [13.50, 52.55], [13.30, 52.55], [13.30, 52.45]],
+          [[13.38, 52.49], [13.42, 52.49], [13.42, 52.51], [13.38, 52.51], [13.38, 52.49]]
+        ]
+      }
+    },
+    {
+      "type": "Feature",
+      "properties": {"name": "Suburbs"},
+      "geometry": {
+        "type": "MultiPolygon",
+        "coordinates": [
+          [[[13.60, 52.45], [13.70, 52.45], [13.70, 52.55], [13.60, 52.55], [13.60, 52.45]]],
+          [[[13.10, 52.45], [13.20, 52.45], [13.20, 52.55], [13.10, 52.55], [13.10, 52.45]]]
+        ]
+      }
+    },
+    {
+      "type": "Feature",
+      "properties": {},
+      "geometry": {
+        "type": "Polygon",
+        "coordinates": [[[13.30, 52.60], [13.50, 52.60], [13.50, 52.70], [13.30, 52.70], [13.30, 52.60]]]
+      }
+    }
+  ]
+}`
+
+func TestZonesFind(t *testing.T) {
+	zones, err := ParseZones([]byte(testZones))
+	if err != nil {
+		t.Fatalf("ParseZones: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string
+	}{
+		{"inside the center", 52.47, 13.35, "Center"},
+		{"in the hole of the center", 52.50, 13.40, ""},
+		{"on the border of the hole", 52.49, 13.40, ""},
+		{"just outside the hole", 52.4899, 13.40, "Center"},
+		{"first polygon of a multipolygon", 52.50, 13.65, "Suburbs"},
+		{"second polygon of a multipolygon", 52.50, 13.15, "Suburbs"},
+		{"between the polygons", 52.50, 13.55, ""},
+		{"unnamed zone", 52.65, 13.40, "Zone 3"},
+		{"far away", 48.85, 2.35, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := zones.Find(tt.lat, tt.lon)
+			got := ""
+			if zone != nil {
+				got = zone.Name
+			}
+			if got != tt.want {
+				t.Errorf("Find(%v, %v) = %q, want %q", tt.lat, tt.lon, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZonesNearest(t *testing.T) {
+	zones, err := ParseZones([]byte(testZones))
+	if err != nil {
+		t.Fatalf("ParseZones: %v", err)
+	}
+
+	// One hundredth of a degree of latitude is about 1112 meters
+	tests := []struct {
+		name         string
+		lat, lon     float64
+		wantZone     string
+		wantDistance float64
+	}{
+		{"inside", 52.47, 13.35, "Center", 0},
+		{"south of the center", 52.44, 13.40, "Center", 1112},
+		{"in the hole", 52.50, 13.40, "Center", 1112},
+		{"closer to the suburbs", 52.50, 13.58, "Suburbs", 1358},
+		{"north of the unnamed zone", 52.71, 13.40, "Zone 3", 1112},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, distance := zones.Nearest(tt.lat, tt.lon)
+			if zone == nil || zone.Name != tt.wantZone {
+				t.Fatalf("Nearest(%v, %v) = %v, want %q", tt.lat, tt.lon, zone, tt.wantZone)
+			}
+			if math.Abs(distance-tt.wantDistance) > 5 {
+				t.Errorf("Nearest(%v, %v) distance = %.0f, want %.0f", tt.lat, tt.lon, distance, tt.wantDistance)
+			}
+		})
+	}
+}
+
+func TestZonesNearestWithoutZones(t *testing.T) {
+	zone, distance := (&Zones{}).Nearest(52.5, 13.4)
+	if zone != nil || !math.IsInf(distance, 1) {
+		t.Errorf("Nearest = %v, %v, want no zone", zone, distance)
+	}
+}
+
+func TestParseZonesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"invalid JSON", `{"type":`},
+		{"not a collection", `{"type": "Feature"}`},
+		{"unsupported geometry", `{"type": "FeatureCollection", "features": [{"geometry": {"type": "Point", "coordinates": [13.4, 52.5]}}]}`},
+		{"invalid coordinates", `{"type": "FeatureCollection", "features": [{"geometry": {"type": "Polygon", "coordinates": [13.4, 52.5]}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseZones([]byte(tt.data)); err == nil {
+				t.Error("ParseZones succeeded, want an error")
+			}
+		})
+	}
+}
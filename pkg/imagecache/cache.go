@@ -0,0 +1,291 @@
+package imagecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxFileSize is the largest image kept, the size limit of photos uploaded to Telegram
+	DefaultMaxFileSize = 10 << 20
+	// DefaultQuota is the disk space the cached images may use before the least recently used ones are removed
+	DefaultQuota = 500 << 20
+)
+
+// ErrTooLarge is returned for images bigger than the maximum file size.
+var ErrTooLarge = errors.New("image is too large")
+
+// ErrNotImage is returned when the downloaded content is not an image, like an HTML error page.
+var ErrNotImage = errors.New("content is not an image")
+
+// extensions maps the sniffed image types to the extension of the cached files
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/bmp":  ".bmp",
+}
+
+// tmpPrefix starts the names of the files being written, which are not part of the cache yet
+const tmpPrefix = ".tmp-"
+
+// Cache keeps downloaded images on disk, named by the hash of their URL so different URLs never collide.
+// Only images within the maximum file size are kept, and the least recently used ones are removed
+// when the cache grows over its quota.
+// The lock only guards the files and their total size, images are downloaded without holding it.
+type Cache struct {
+	dir         string
+	maxFileSize int64
+	quota       int64
+	client      *http.Client
+
+	mu sync.Mutex
+	// size is the total size of the cached images, known once the cache directory was scanned
+	size    int64
+	scanned bool
+}
+
+// New creates a cache keeping its images in dir.
+func New(dir string, maxFileSize, quota int64) *Cache {
+	return &Cache{
+		dir:         dir,
+		maxFileSize: maxFileSize,
+		quota:       quota,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Path returns the path of the cached copy of the image, downloading it first if needed.
+// Images are grouped in subdirectories, like "products" or "accounts".
+func (c *Cache) Path(imageURL, group string) (string, error) {
+	if path, ok, err := c.touch(imageURL, group); ok || err != nil {
+		return path, err
+	}
+
+	data, contentType, err := c.download(imageURL)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another request may have cached the image during the download
+	if path, ok := c.lookup(imageURL, group); ok {
+		return path, nil
+	}
+	if err := c.loadSize(); err != nil {
+		return "", err
+	}
+	path := filepath.Join(c.dir, group, key(imageURL)+extensions[contentType])
+	if err := writeAtomically(path, data); err != nil {
+		return "", err
+	}
+	c.size += int64(len(data))
+	if c.size > c.quota {
+		if err := c.evict(path); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// touch returns the path of the cached copy of the image, if there is one, and marks it as used.
+func (c *Cache) touch(imageURL, group string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path, ok := c.lookup(imageURL, group)
+	if !ok {
+		return "", false, nil
+	}
+	// The modification time records the last use for the eviction
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return "", false, fmt.Errorf("touching cached image: %w", err)
+	}
+	return path, true, nil
+}
+
+// Remove deletes the cached copy of the image, so the next use downloads it again.
+func (c *Cache) Remove(imageURL, group string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path, ok := c.lookup(imageURL, group)
+	if !ok {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("removing cached image: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing cached image: %w", err)
+	}
+	if c.scanned {
+		c.size -= info.Size()
+	}
+	return nil
+}
+
+// key returns the name of the cached file of an image without its extension.
+func key(imageURL string) string {
+	sum := sha256.Sum256([]byte(imageURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup finds the cached file of an image, whatever its extension.
+func (c *Cache) lookup(imageURL, group string) (string, bool) {
+	matches, err := filepath.Glob(filepath.Join(c.dir, group, key(imageURL)+".*"))
+	if err != nil || len(matches) == 0 {
+		return "", false
+	}
+	return matches[0], true
+}
+
+// download fetches an image and checks its size and its type, sniffed from the content rather than trusted from the headers.
+func (c *Cache) download(imageURL string) ([]byte, string, error) {
+	resp, err := c.client.Get(imageURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("getting http response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("downloading %s: unexpected status %s", imageURL, resp.Status)
+	}
+	if resp.ContentLength > c.maxFileSize {
+		return nil, "", fmt.Errorf("downloading %s: %w", imageURL, ErrTooLarge)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.maxFileSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("reading response body: %w", err)
+	}
+	if int64(len(data)) > c.maxFileSize {
+		return nil, "", fmt.Errorf("downloading %s: %w", imageURL, ErrTooLarge)
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := extensions[contentType]; !ok {
+		return nil, "", fmt.Errorf("downloading %s: %w (%s)", imageURL, ErrNotImage, contentType)
+	}
+	return data, contentType, nil
+}
+
+// writeAtomically writes a file through a temporary file renamed into place, so a crash never leaves it half written.
+func writeAtomically(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, tmpPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing image: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing image: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("replacing image: %w", err)
+	}
+	return nil
+}
+
+// cachedFile is an image found in the cache directory
+type cachedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// scan lists the cached images and returns their total size. Files still being written are left out.
+func (c *Cache) scan() ([]cachedFile, int64, error) {
+	var files []cachedFile
+	var total int64
+	err := filepath.WalkDir(c.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == c.dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), tmpPrefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, cachedFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("scanning image cache: %w", err)
+	}
+	return files, total, nil
+}
+
+// loadSize scans the cache directory for the images left by earlier runs the first time the size is needed.
+func (c *Cache) loadSize() error {
+	if c.scanned {
+		return nil
+	}
+	_, total, err := c.scan()
+	if err != nil {
+		return err
+	}
+	c.size, c.scanned = total, true
+	return nil
+}
+
+// evict removes the least recently used images until the cache fits in its quota. The image at keep is never removed.
+// It scans the directory again, so the running size also catches up with files removed by hand.
+func (c *Cache) evict(keep string) error {
+	files, total, err := c.scan()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, file := range files {
+		if total <= c.quota {
+			break
+		}
+		if file.path == keep {
+			continue
+		}
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			c.size = total
+			return fmt.Errorf("evicting cached image: %w", err)
+		}
+		total -= file.size
+	}
+	c.size = total
+	return nil
+}
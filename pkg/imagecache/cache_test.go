@@ -0,0 +1,206 @@
+package imagecache
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pngData returns size bytes of content sniffed as a PNG image.
+func pngData(size int) []byte {
+	data := bytes.Repeat([]byte{0}, size)
+	copy(data, "\x89PNG\r\n\x1a\n")
+	return data
+}
+
+// newTestServer serves the given bodies by path, answering 404 for the other paths.
+func newTestServer(t *testing.T, bodies map[string][]byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPath(t *testing.T) {
+	server := newTestServer(t, map[string][]byte{
+		"/milk.png":  pngData(100),
+		"/error":     []byte("<!DOCTYPE html><html><body>Internal error</body></html>"),
+		"/large.png": pngData(300),
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+		wantExt string
+	}{
+		{name: "image", path: "/milk.png", wantExt: ".png"},
+		{name: "html error page", path: "/error", wantErr: ErrNotImage},
+		{name: "over the size limit", path: "/large.png", wantErr: ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := New(t.TempDir(), 200, 1000)
+			path, err := cache.Path(server.URL+tt.path, "products")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Path error = %v, want %v", err, tt.wantErr)
+				}
+				if files, _, _ := cache.scan(); len(files) != 0 {
+					t.Errorf("rejected image left %d files in the cache", len(files))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Path: %v", err)
+			}
+			if filepath.Ext(path) != tt.wantExt || filepath.Base(filepath.Dir(path)) != "products" {
+				t.Errorf("Path = %q, want a %s file in the products group", path, tt.wantExt)
+			}
+		})
+	}
+}
+
+func TestPathWritesAtomically(t *testing.T) {
+	server := newTestServer(t, map[string][]byte{"/milk.png": pngData(100)})
+	dir := t.TempDir()
+	cache := New(dir, 200, 1000)
+
+	path, err := cache.Path(server.URL+"/milk.png", "products")
+	if err != nil {
+		t.Fatalf("Path: %v", err)
+	}
+	if err := writeAtomically(path, pngData(150)); err != nil {
+		t.Fatalf("writeAtomically: %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "products"))
+	if err != nil {
+		t.Fatalf("reading cache: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), tmpPrefix) {
+			t.Errorf("temporary file %s was left behind", entry.Name())
+		}
+	}
+	if len(entries) != 1 {
+		t.Errorf("cache holds %d files, want 1", len(entries))
+	}
+	if data, _ := os.ReadFile(path); len(data) != 150 {
+		t.Errorf("image has %d bytes after the replacement, want 150", len(data))
+	}
+}
+
+func TestPathEvictsLeastRecentlyUsed(t *testing.T) {
+	server := newTestServer(t, map[string][]byte{
+		"/a.png": pngData(100),
+		"/b.png": pngData(100),
+		"/c.png": pngData(100),
+	})
+	cache := New(t.TempDir(), 200, 250)
+
+	paths := make(map[string]string)
+	for i, name := range []string{"a", "b"} {
+		path, err := cache.Path(server.URL+"/"+name+".png", "products")
+		if err != nil {
+			t.Fatalf("Path(%s): %v", name, err)
+		}
+		// Spread the uses apart, file times are not precise enough for uses in a row
+		used := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(path, used, used)
+		paths[name] = path
+	}
+	if cache.size != 200 {
+		t.Errorf("running size = %d, want 200", cache.size)
+	}
+
+	// Using a again makes b the least recently used image
+	if _, err := cache.Path(server.URL+"/a.png", "products"); err != nil {
+		t.Fatalf("Path(a): %v", err)
+	}
+	paths["c"], _ = cache.Path(server.URL+"/c.png", "products")
+
+	for name, wantKept := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, err := os.Stat(paths[name]); (err == nil) != wantKept {
+			t.Errorf("image %s kept = %v, want %v", name, err == nil, wantKept)
+		}
+	}
+	if cache.size != 200 {
+		t.Errorf("running size = %d after the eviction, want 200", cache.size)
+	}
+
+	if err := cache.Remove(server.URL+"/a.png", "products"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if cache.size != 100 {
+		t.Errorf("running size = %d after the removal, want 100", cache.size)
+	}
+}
+
+func TestPathCountsImagesOfEarlierRuns(t *testing.T) {
+	server := newTestServer(t, map[string][]byte{"/a.png": pngData(100), "/b.png": pngData(100)})
+	dir := t.TempDir()
+	if _, err := New(dir, 200, 1000).Path(server.URL+"/a.png", "products"); err != nil {
+		t.Fatalf("Path(a): %v", err)
+	}
+
+	cache := New(dir, 200, 1000)
+	if _, err := cache.Path(server.URL+"/b.png", "products"); err != nil {
+		t.Fatalf("Path(b): %v", err)
+	}
+	if cache.size != 200 {
+		t.Errorf("running size = %d, want both images", cache.size)
+	}
+}
+
+func TestPathDownloadsWithoutLock(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.png" {
+			<-release
+		}
+		w.Write(pngData(100))
+	}))
+	t.Cleanup(server.Close)
+	cache := New(t.TempDir(), 200, 1000)
+
+	slow := make(chan error)
+	go func() {
+		_, err := cache.Path(server.URL+"/slow.png", "products")
+		slow <- err
+	}()
+	// Give the slow download time to start
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		_, err := cache.Path(server.URL+"/fast.png", "products")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Path: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("a slow download held up the other images")
+	}
+
+	close(release)
+	if err := <-slow; err != nil {
+		t.Errorf("Path(slow): %v", err)
+	}
+}
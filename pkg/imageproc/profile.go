@@ -0,0 +1,162 @@
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+
+	// Register the formats accepted for profile images
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	// MaxProfileImageSize is the largest profile image the backend accepts, in bytes
+	MaxProfileImageSize = 2048 << 10
+	// ProfileImageSide is the side of the square profile images, in pixels
+	ProfileImageSide = 512
+	// minProfileImageSide stops shrinking an image that still does not fit in MaxProfileImageSize
+	minProfileImageSide = 64
+	// MaxProfileImagePixels bounds the decoded size of an upload. A few KB of PNG can claim
+	// a huge canvas, and decoding it would take gigabytes of memory.
+	MaxProfileImagePixels = 40_000_000
+)
+
+// ErrUnsupportedImage is returned for content that cannot be decoded as a JPEG, PNG or GIF image.
+var ErrUnsupportedImage = errors.New("unsupported image format")
+
+// ErrTooManyPixels is returned for images with more than MaxProfileImagePixels pixels.
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// ProcessProfileImage prepares an uploaded picture to become a profile image:
+// it rejects pictures over MaxProfileImagePixels, crops the center square, turns it upright
+// according to its EXIF orientation, scales it down to ProfileImageSide and encodes it as a JPEG under MaxProfileImageSize.
+func ProcessProfileImage(data []byte) ([]byte, error) {
+	// The header tells the size before any pixel is decoded
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	if pixels := int64(config.Width) * int64(config.Height); pixels > MaxProfileImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d, up to %d megapixels are accepted",
+			ErrTooManyPixels, config.Width, config.Height, MaxProfileImagePixels/1_000_000)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	// The center square stays the center square once turned upright, so crop first and copy fewer pixels
+	square := orient(toRGBA(img, centerSquare(img.Bounds())), exifOrientation(data))
+
+	side := square.Bounds().Dx()
+	if side > ProfileImageSide {
+		side = ProfileImageSide
+	}
+	for ; side >= minProfileImageSide; side /= 2 {
+		resized := resize(square, side)
+		for quality := 90; quality >= 50; quality -= 10 {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: quality}); err != nil {
+				return nil, fmt.Errorf("encoding image: %w", err)
+			}
+			if buf.Len() <= MaxProfileImageSize {
+				return buf.Bytes(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("image does not fit in %d KB", MaxProfileImageSize>>10)
+}
+
+// toRGBA copies the area r of an image onto a white background, so transparent areas stay white in the JPEG.
+func toRGBA(img image.Image, r image.Rectangle) *image.RGBA {
+	rgba := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), img, r.Min, draw.Over)
+	return rgba
+}
+
+// orient turns an image upright according to its EXIF orientation.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		// These orientations are rotated by a quarter turn, which swaps the sides
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirrored
+				sx, sy = w-1-x, y
+			case 3: // Upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // Mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // Transposed
+				sx, sy = y, x
+			case 6: // Needs a quarter turn clockwise
+				sx, sy = y, h-1-x
+			case 7: // Transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // Needs a quarter turn counterclockwise
+				sx, sy = w-1-y, x
+			}
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
+
+// centerSquare returns the largest square in the center of the bounds of an image.
+func centerSquare(bounds image.Rectangle) image.Rectangle {
+	w, h := bounds.Dx(), bounds.Dy()
+	side := w
+	if h < side {
+		side = h
+	}
+	min := bounds.Min.Add(image.Pt((w-side)/2, (h-side)/2))
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(side, side))}
+}
+
+// resize scales a square image down to side pixels, averaging the source pixels covered by each new pixel.
+// Images already smaller are returned unchanged.
+func resize(src *image.RGBA, side int) *image.RGBA {
+	bounds := src.Bounds()
+	srcSide := bounds.Dx()
+	if srcSide <= side {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	for y := 0; y < side; y++ {
+		y0, y1 := y*srcSide/side, (y+1)*srcSide/side
+		for x := 0; x < side; x++ {
+			x0, x1 := x*srcSide/side, (x+1)*srcSide/side
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := src.RGBAAt(bounds.Min.X+sx, bounds.Min.Y+sy)
+					r += uint32(c.R)
+					g += uint32(c.G)
+					b += uint32(c.B)
+					a += uint32(c.A)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,165 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// pngHeader returns the start of a PNG file claiming the given size, enough for image.DecodeConfig.
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	ihdr[12], ihdr[13] = 8, 2 // 8-bit RGB
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(13))
+	buf.Write(ihdr)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(ihdr))
+	return buf.Bytes()
+}
+
+// halves returns an image whose left half is red and right half is blue.
+func halves(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an EXIF segment with the orientation right after the start of a JPEG.
+func withOrientation(data []byte, orientation uint16) []byte {
+	var exif bytes.Buffer
+	exif.WriteString("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08")
+	binary.Write(&exif, binary.BigEndian, []uint16{1, orientationTag, 3})
+	binary.Write(&exif, binary.BigEndian, []uint32{1})
+	binary.Write(&exif, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(&exif, binary.BigEndian, []uint32{0})
+
+	var buf bytes.Buffer
+	buf.Write(data[:2])
+	buf.Write([]byte{0xFF, 0xE1})
+	binary.Write(&buf, binary.BigEndian, uint16(exif.Len()+2))
+	buf.Write(exif.Bytes())
+	buf.Write(data[2:])
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("encoding JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encoding PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessProfileImageRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "not an image", data: []byte("<html>Not found</html>"), wantErr: ErrUnsupportedImage},
+		{name: "huge canvas", data: pngHeader(50000, 50000), wantErr: ErrTooManyPixels},
+		{name: "just over the budget", data: pngHeader(MaxProfileImagePixels/1000+1, 1000), wantErr: ErrTooManyPixels},
+		{name: "truncated image", data: pngHeader(100, 100), wantErr: ErrUnsupportedImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ProcessProfileImage(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ProcessProfileImage error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessProfileImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		wantSide int
+		// The colors expected near the top and the bottom of the middle column
+		wantTop, wantBottom string
+	}{
+		{name: "wide PNG", data: encodePNG(t, halves(1200, 600)), wantSide: ProfileImageSide},
+		{name: "small tall PNG", data: encodePNG(t, halves(100, 300)), wantSide: 100},
+		{
+			name:       "JPEG turned a quarter",
+			data:       withOrientation(encodeJPEG(t, halves(256, 128)), 6),
+			wantSide:   128,
+			wantTop:    "red",
+			wantBottom: "blue",
+		},
+		{
+			name:       "JPEG turned three quarters",
+			data:       withOrientation(encodeJPEG(t, halves(256, 128)), 8),
+			wantSide:   128,
+			wantTop:    "blue",
+			wantBottom: "red",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ProcessProfileImage(tt.data)
+			if err != nil {
+				t.Fatalf("ProcessProfileImage: %v", err)
+			}
+			img, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("decoding the result: %v", err)
+			}
+			side := img.Bounds().Dx()
+			if side != tt.wantSide || img.Bounds().Dy() != tt.wantSide {
+				t.Fatalf("result is %v, want a %d pixel square", img.Bounds().Size(), tt.wantSide)
+			}
+			if tt.wantTop == "" {
+				return
+			}
+			if got := colorName(img.At(side/2, 2)); got != tt.wantTop {
+				t.Errorf("top is %s, want %s", got, tt.wantTop)
+			}
+			if got := colorName(img.At(side/2, side-3)); got != tt.wantBottom {
+				t.Errorf("bottom is %s, want %s", got, tt.wantBottom)
+			}
+		})
+	}
+}
+
+// colorName tells red from blue in a JPEG, whose colors are never exact.
+func colorName(c color.Color) string {
+	r, _, b, _ := c.RGBA()
+	switch {
+	case r > 0xC000 && b < 0x4000:
+		return "red"
+	case b > 0xC000 && r < 0x4000:
+		return "blue"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,170 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Page geometry of an A4 page in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+	Margin     = 50.0
+)
+
+// ContentWidth is the usable width between the left and right margins.
+const ContentWidth = PageWidth - 2*Margin
+
+const (
+	regularFont = "F1"
+	boldFont    = "F2"
+	lineSpacing = 1.4
+)
+
+// Column describes a single cell of a table row.
+type Column struct {
+	Text  string
+	Width float64
+	// AlignRight aligns the text to the right edge of the column
+	AlignRight bool
+}
+
+// Document is a simple text-only PDF document that uses the standard Helvetica fonts.
+// Text is written in WinAnsiEncoding, so characters outside of Western European scripts are shown as '?'.
+// Content is laid out from top to bottom and new pages are added automatically.
+type Document struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+// New creates an empty document with a single page.
+func New() *Document {
+	d := &Document{}
+	d.addPage()
+	return d
+}
+
+// addPage starts a new page and moves the cursor to its top margin.
+func (d *Document) addPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = PageHeight - Margin
+}
+
+// currentPage returns the content stream of the page being written.
+func (d *Document) currentPage() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// ensureSpace adds a new page when less than height points are left on the current one.
+func (d *Document) ensureSpace(height float64) {
+	if d.y-height < Margin {
+		d.addPage()
+	}
+}
+
+// writeText places text at the given position without moving the cursor.
+func (d *Document) writeText(x, y float64, font string, size float64, text string) {
+	fmt.Fprintf(d.currentPage(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escape(text))
+}
+
+// Heading writes a line of bold text with the given font size.
+func (d *Document) Heading(text string, size float64) {
+	height := size * lineSpacing
+	d.ensureSpace(height)
+	d.y -= size
+	d.writeText(Margin, d.y, boldFont, size, truncate(boldFont, text, ContentWidth, size))
+	d.y -= height - size
+}
+
+// Text writes a line of regular text with the given font size.
+// Text wider than the page is wrapped on word boundaries.
+func (d *Document) Text(text string, size float64) {
+	for _, line := range wrap(regularFont, text, ContentWidth, size) {
+		height := size * lineSpacing
+		d.ensureSpace(height)
+		d.y -= size
+		d.writeText(Margin, d.y, regularFont, size, line)
+		d.y -= height - size
+	}
+}
+
+// Row writes a single table row. Text that does not fit into its column is truncated.
+func (d *Document) Row(columns []Column, size float64, bold bool) {
+	font := regularFont
+	if bold {
+		font = boldFont
+	}
+
+	height := size * lineSpacing
+	d.ensureSpace(height)
+	d.y -= size
+
+	x := Margin
+	for _, column := range columns {
+		text := truncate(font, column.Text, column.Width-4, size)
+		textX := x
+		if column.AlignRight {
+			textX = x + column.Width - 4 - textWidth(font, text, size)
+		}
+		d.writeText(textX, d.y, font, size, text)
+		x += column.Width
+	}
+	d.y -= height - size
+}
+
+// Rule draws a horizontal line across the content width.
+func (d *Document) Rule() {
+	d.ensureSpace(8)
+	d.y -= 4
+	fmt.Fprintf(d.currentPage(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", Margin, d.y, PageWidth-Margin, d.y)
+	d.y -= 4
+}
+
+// Space moves the cursor down by the given number of points.
+func (d *Document) Space(height float64) {
+	d.y -= height
+	if d.y < Margin {
+		d.addPage()
+	}
+}
+
+// Bytes renders the document into a PDF file.
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	writeObject := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are the catalog, the page tree and the two fonts; every page then takes two objects
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*i))
+	}
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range d.pages {
+		writeObject(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, regularFont, boldFont, 6+2*i,
+		))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+
+	return buf.Bytes()
+}
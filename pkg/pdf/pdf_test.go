@@ -0,0 +1,161 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"latin", "Order 42", "Order 42"},
+		{"accents", "Crème brûlée", "Cr\xe8me br\xfbl\xe9e"},
+		{"win ansi extras", "12.50 € – “fresh”", "12.50 \x80 \x96 \x93fresh\x94"},
+		{"cyrillic", "Сыр 45%", "??? 45%"},
+		{"emoji", "Pizza 🍕 ready ✅", "Pizza  ready "},
+		{"line breaks", "one\ntwo\tthree", "one two three"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(encode(tt.text)); got != tt.want {
+				t.Errorf("encode(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentUsesStandardFonts(t *testing.T) {
+	doc := New()
+	doc.Text(`Total (with "tax") \ 12.50 €`, 10)
+	out := doc.Bytes()
+
+	for _, font := range []string{"/BaseFont /Helvetica ", "/BaseFont /Helvetica-Bold "} {
+		if !bytes.Contains(out, []byte(font+"/Encoding /WinAnsiEncoding")) {
+			t.Errorf("document does not use %s with WinAnsiEncoding", font)
+		}
+	}
+	if want := `(Total \(with "tax"\) \\ 12.50 ` + "\x80) Tj"; !bytes.Contains(doc.pages[0].Bytes(), []byte(want)) {
+		t.Errorf("content stream %q does not contain %q", doc.pages[0].String(), want)
+	}
+}
+
+func TestDocumentXrefOffsets(t *testing.T) {
+	doc := New()
+	doc.Heading("Orders", 16)
+	for i := 0; i < 120; i++ {
+		doc.Row([]Column{{Text: strconv.Itoa(i), Width: 100}, {Text: "Product", Width: 200, AlignRight: true}}, 10, i == 0)
+	}
+	if len(doc.pages) < 2 {
+		t.Fatalf("expected the rows to span several pages, got %d", len(doc.pages))
+	}
+	out := doc.Bytes()
+
+	start := bytes.LastIndex(out, []byte("startxref\n"))
+	if start < 0 {
+		t.Fatal("missing startxref")
+	}
+	xrefOffset, err := strconv.Atoi(strings.Fields(string(out[start+len("startxref\n"):]))[0])
+	if err != nil {
+		t.Fatalf("invalid startxref: %v", err)
+	}
+	if !bytes.HasPrefix(out[xrefOffset:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xrefOffset)
+	}
+
+	lines := strings.Split(string(out[xrefOffset:]), "\n")
+	var count int
+	fmt.Sscanf(lines[1], "0 %d", &count)
+	if want := 4 + 2*len(doc.pages); count != want+1 {
+		t.Fatalf("xref has %d entries, want %d", count, want+1)
+	}
+	for i := 1; i < count; i++ {
+		offset, err := strconv.Atoi(lines[2+i][:10])
+		if err != nil {
+			t.Fatalf("invalid xref entry %q", lines[2+i])
+		}
+		if header := fmt.Sprintf("%d 0 obj\n", i); !bytes.HasPrefix(out[offset:], []byte(header)) {
+			t.Errorf("object %d: offset %d does not point at its header", i, offset)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	for _, font := range []string{regularFont, boldFont} {
+		for c := byte(0x20); c < 0x7F; c++ {
+			if charWidth(font, c) == 0 {
+				t.Errorf("%s has no width for %q", font, c)
+			}
+		}
+	}
+
+	tests := []struct {
+		font string
+		text string
+		want float64
+	}{
+		{regularFont, "Milk", 17.77},
+		{boldFont, "Milk", 19.45},
+		{regularFont, "~", 5.84},
+		{regularFont, "Сыр", 16.68},
+	}
+	for _, tt := range tests {
+		if got := textWidth(tt.font, tt.text, 10); fmt.Sprintf("%.2f", got) != fmt.Sprintf("%.2f", tt.want) {
+			t.Errorf("textWidth(%s, %q) = %.2f, want %.2f", tt.font, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxWidth float64
+		want     string
+	}{
+		{"fits", "Milk", 100, "Milk"},
+		{"shortened", "Chocolate milkshake", 60, "Chocolate..."},
+		{"nothing fits", "Milk", 1, "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(regularFont, tt.text, tt.maxWidth, 10)
+			if got != tt.want {
+				t.Errorf("truncate(%q, %v) = %q, want %q", tt.text, tt.maxWidth, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"single line", "Two apples", []string{"Two apples"}},
+		{"wrapped", "one two three four five six", []string{"one two three", "four five six"}},
+		{"paragraphs", "first\n\nsecond", []string{"first", "", "second"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrap(regularFont, tt.text, 80, 10)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("wrap(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			for _, line := range got {
+				if width := textWidth(regularFont, line, 10); width > 80 {
+					t.Errorf("line %q is %v points wide", line, width)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package pdf
+
+import (
+	"strings"
+)
+
+// winAnsiExtras maps the characters outside of Latin-1 that exist in WinAnsiEncoding.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// encode converts text to WinAnsiEncoding. Characters the standard fonts cannot show
+// are replaced with '?', emoji are dropped and line breaks and tabs become spaces.
+func encode(text string) []byte {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			encoded = append(encoded, ' ')
+		case (r >= 0x20 && r < 0x7F) || (r >= 0xA0 && r <= 0xFF):
+			encoded = append(encoded, byte(r))
+		case winAnsiExtras[r] != 0:
+			encoded = append(encoded, winAnsiExtras[r])
+		case r >= 0x1F000 || (r >= 0x2600 && r <= 0x27BF) || r == 0xFE0F || r == 0x200D:
+			// Emoji and their modifiers
+		default:
+			encoded = append(encoded, '?')
+		}
+	}
+	return encoded
+}
+
+// escape encodes text and escapes the characters that have a special meaning inside PDF strings.
+func escape(text string) string {
+	var sb strings.Builder
+	for _, c := range encode(text) {
+		if c == '(' || c == ')' || c == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// asciiWidths holds the widths of the printable ASCII characters, from the space to the tilde,
+// in thousandths of the font size as published in the metrics of the standard fonts.
+var asciiWidths = map[string][95]float64{
+	regularFont: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	boldFont: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// charWidth returns the width of a WinAnsi character in thousandths of the font size.
+// Characters above ASCII are approximated by the width of a typical capital or small letter.
+func charWidth(font string, c byte) float64 {
+	switch {
+	case c >= 0x20 && c < 0x7F:
+		return asciiWidths[font][c-0x20]
+	case c >= 0xC0 && c <= 0xDE:
+		return 722
+	case font == boldFont:
+		return 611
+	default:
+		return 556
+	}
+}
+
+// textWidth returns the width of text in points for the given font and size.
+func textWidth(font string, text string, size float64) float64 {
+	width := 0.0
+	for _, c := range encode(text) {
+		width += charWidth(font, c)
+	}
+	return width * size / 1000
+}
+
+// truncate shortens text with an ellipsis so that it fits into maxWidth points.
+func truncate(font string, text string, maxWidth float64, size float64) string {
+	if textWidth(font, text, size) <= maxWidth {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && textWidth(font, string(runes)+"...", size) > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimRight(string(runes), " ") + "..."
+}
+
+// wrap splits text into lines that fit into maxWidth points, breaking on spaces where possible.
+func wrap(font string, text string, maxWidth float64, size float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, word := range words[1:] {
+			if textWidth(font, line+" "+word, size) > maxWidth {
+				lines = append(lines, truncate(font, line, maxWidth, size))
+				line = word
+				continue
+			}
+			line += " " + word
+		}
+		lines = append(lines, truncate(font, line, maxWidth, size))
+	}
+	return lines
+}
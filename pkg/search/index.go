@@ -0,0 +1,213 @@
+package search
+
+import (
+	"my-telegram-bot/pkg/api"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// descriptionWeight is how much a match in the description counts compared to a match in the name
+const descriptionWeight = 0.5
+
+// document is a product prepared for matching
+type document struct {
+	product     api.Product
+	name        []string
+	description []string
+}
+
+// Index finds products by name and description, tolerating typos, inflections and transliteration.
+// It is safe for concurrent use: searches keep running on the old catalog while a new one is loaded.
+type Index struct {
+	mu         sync.RWMutex
+	documents  []document
+	vocabulary map[string]word
+	syncedAt   time.Time
+	syncing    bool
+}
+
+// word is a term of the product names as spelled in the catalog, used for the suggestions
+type word struct {
+	spelling string
+	count    int
+}
+
+// Result is a product found by the index with the relevance of the match.
+type Result struct {
+	Product api.Product
+	Score   float64
+}
+
+// NewIndex creates an empty index.
+func NewIndex() *Index {
+	return &Index{vocabulary: make(map[string]word)}
+}
+
+// Load replaces the catalog of the index.
+func (idx *Index) Load(products []api.Product) {
+	documents := make([]document, 0, len(products))
+	vocabulary := make(map[string]word)
+	for _, product := range products {
+		documents = append(documents, document{
+			product:     product,
+			name:        terms(product.Name),
+			description: terms(product.Description),
+		})
+		for _, w := range words(product.Name) {
+			t := term(w)
+			entry := vocabulary[t]
+			if entry.spelling == "" {
+				entry.spelling = w
+			}
+			entry.count++
+			vocabulary[t] = entry
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.documents = documents
+	idx.vocabulary = vocabulary
+	idx.syncedAt = time.Now()
+}
+
+// Len returns the number of products in the index.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.documents)
+}
+
+// BeginSync reports whether the catalog is older than maxAge and no other sync is running.
+// When it returns true the caller must load the new catalog or call EndSync.
+func (idx *Index) BeginSync(maxAge time.Duration) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.syncing || time.Since(idx.syncedAt) < maxAge {
+		return false
+	}
+	idx.syncing = true
+	return true
+}
+
+// EndSync finishes a sync started with BeginSync, loading products unless the sync failed.
+func (idx *Index) EndSync(products []api.Product, err error) {
+	if err == nil {
+		idx.Load(products)
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.syncing = false
+}
+
+// Search returns the products matching every word of the query, the most relevant first.
+func (idx *Index) Search(query string) []Result {
+	queryTerms := terms(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var results []Result
+	for _, doc := range idx.documents {
+		score := 0.0
+		for _, q := range queryTerms {
+			termScore := bestMatch(q, doc.name)
+			if s := descriptionWeight * bestMatch(q, doc.description); s > termScore {
+				termScore = s
+			}
+			if termScore == 0 {
+				score = 0
+				break
+			}
+			score += termScore
+		}
+		if score > 0 {
+			results = append(results, Result{Product: doc.product, Score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Product.Name < results[j].Product.Name
+	})
+	return results
+}
+
+// Suggest returns the query with its misspelled words replaced by the closest words of the product names,
+// or an empty string when there is nothing to correct.
+func (idx *Index) Suggest(query string) string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	queryWords := words(query)
+	corrected := false
+	for i, w := range queryWords {
+		t := term(w)
+		if idx.isKnown(t) {
+			continue
+		}
+		best, bestDistance, bestCount := "", 0, 0
+		for candidate, entry := range idx.vocabulary {
+			d := distance(t, candidate)
+			if d > maxTypos(t) {
+				continue
+			}
+			// Prefer the closest word, then the most frequent one, then the first alphabetically to stay deterministic
+			if best == "" || d < bestDistance || (d == bestDistance && entry.count > bestCount) ||
+				(d == bestDistance && entry.count == bestCount && entry.spelling < best) {
+				best, bestDistance, bestCount = entry.spelling, d, entry.count
+			}
+		}
+		if best != "" {
+			queryWords[i] = best
+			corrected = true
+		}
+	}
+	if !corrected {
+		return ""
+	}
+	return strings.Join(queryWords, " ")
+}
+
+// isKnown reports whether a term is a word of the product names or the beginning of one.
+func (idx *Index) isKnown(t string) bool {
+	if _, ok := idx.vocabulary[t]; ok {
+		return true
+	}
+	for candidate := range idx.vocabulary {
+		if strings.HasPrefix(candidate, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// bestMatch scores how well a query term matches the best of the terms of a document:
+// 1 for the same term, less for a term starting with it, less again for a term with typos, and 0 for no match.
+func bestMatch(q string, documentTerms []string) float64 {
+	best := 0.0
+	for _, t := range documentTerms {
+		score := 0.0
+		switch {
+		case t == q:
+			return 1
+		case len(q) >= 2 && strings.HasPrefix(t, q):
+			score = 0.8
+		default:
+			if d := distance(q, t); d <= maxTypos(q) {
+				score = 0.9 - 0.15*float64(d)
+			}
+		}
+		if score > best {
+			best = score
+		}
+	}
+	return best
+}
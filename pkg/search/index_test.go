@@ -0,0 +1,114 @@
+package search
+
+import (
+	"errors"
+	"my-telegram-bot/pkg/api"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testCatalog is a small catalog with names and descriptions in English and Russian
+var testCatalog = []api.Product{
+	{ID: 1, Name: "Goat cheese", Description: "Soft cheese from goat milk"},
+	{ID: 2, Name: "Cheddar", Description: "Aged cheese"},
+	{ID: 3, Name: "Whole milk", Description: "Fresh cow milk"},
+	{ID: 4, Name: "Сыр Российский"},
+	{ID: 5, Name: "Strawberries", Description: "Sweet berries"},
+}
+
+func newTestIndex() *Index {
+	idx := NewIndex()
+	idx.Load(testCatalog)
+	return idx
+}
+
+func TestSearch(t *testing.T) {
+	idx := newTestIndex()
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{name: "name before description", query: "cheese", want: []int{1, 2}},
+		{name: "description match", query: "milk", want: []int{3, 1}},
+		{name: "typo", query: "chese", want: []int{1, 2}},
+		{name: "every word has to match", query: "goat milk", want: []int{1}},
+		{name: "prefix", query: "straw", want: []int{5}},
+		{name: "inflection", query: "strawberry", want: []int{5}},
+		{name: "cyrillic", query: "сыр", want: []int{4}},
+		{name: "transliterated", query: "syr", want: []int{4}},
+		{name: "no match", query: "bread", want: nil},
+		{name: "empty query", query: " ", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, result := range idx.Search(tt.query) {
+				got = append(got, result.Product.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchScores(t *testing.T) {
+	results := newTestIndex().Search("chese")
+	if len(results) != 2 {
+		t.Fatalf("Search found %d products, want 2", len(results))
+	}
+	if results[0].Score != 0.75 || results[1].Score != 0.375 {
+		t.Errorf("scores = %v, %v, want a typo in the name to count twice a typo in the description", results[0].Score, results[1].Score)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	idx := newTestIndex()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"chese", "cheese"},
+		{"goat chese", "goat cheese"},
+		{"milc", "milk"},
+		{"syrr", "сыр"},
+		{"cheese", ""},
+		{"chee", ""},
+		{"xyzzy", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := idx.Suggest(tt.query); got != tt.want {
+			t.Errorf("Suggest(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	idx := NewIndex()
+	if !idx.BeginSync(time.Hour) {
+		t.Fatal("an empty index did not start a sync")
+	}
+	if idx.BeginSync(time.Hour) {
+		t.Fatal("a second sync started while the first one was running")
+	}
+	idx.EndSync(testCatalog, nil)
+	if idx.Len() != len(testCatalog) {
+		t.Fatalf("Len = %d after the sync, want %d", idx.Len(), len(testCatalog))
+	}
+
+	if idx.BeginSync(time.Hour) {
+		t.Fatal("a fresh catalog was synced again")
+	}
+	if !idx.BeginSync(0) {
+		t.Fatal("a forced sync did not start")
+	}
+	idx.EndSync(nil, errors.New("backend down"))
+	if idx.Len() != len(testCatalog) {
+		t.Errorf("Len = %d after a failed sync, want the old catalog of %d", idx.Len(), len(testCatalog))
+	}
+}
@@ -0,0 +1,107 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"cheese", "cheese", 0},
+		{"chese", "cheese", 1},
+		{"cheeze", "cheese", 1},
+		{"hceese", "cheese", 1},
+		{"milk", "silk", 1},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"сыр", "сир", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"cheeses", "cheese"},
+		{"berries", "berry"},
+		{"peaches", "peach"},
+		{"boxes", "box"},
+		{"eggs", "egg"},
+		{"glass", "glass"},
+		{"hummus", "hummus"},
+		{"bus", "bus"},
+		{"smoking", "smok"},
+		{"smoked", "smok"},
+		{"milk", "milk"},
+	}
+	for _, tt := range tests {
+		if got := stem(tt.word); got != tt.want {
+			t.Errorf("stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"сыр", "syr"},
+		{"щука", "shchuka"},
+		{"йогурт", "yogurt"},
+		{"crème", "creme"},
+		{"straße", "strasse"},
+		{"milk", "milk"},
+		{"200g", "200g"},
+	}
+	for _, tt := range tests {
+		if got := transliterate(tt.word); got != tt.want {
+			t.Errorf("transliterate(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Goat-cheese, 200g!", []string{"goat", "cheese", "200g"}},
+		{"Сыры и Cheeses", []string{"syry", "i", "cheese"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		if got := terms(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("terms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMaxTypos(t *testing.T) {
+	tests := []struct {
+		term string
+		want int
+	}{
+		{"tea", 0},
+		{"milk", 1},
+		{"cheese", 1},
+		{"chocolate", 2},
+		{"сыр", 0},
+	}
+	for _, tt := range tests {
+		if got := maxTypos(tt.term); got != tt.want {
+			t.Errorf("maxTypos(%q) = %d, want %d", tt.term, got, tt.want)
+		}
+	}
+}
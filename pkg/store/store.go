@@ -0,0 +1,132 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore keeps JSON values grouped in buckets, one file per bucket inside a directory.
+// It is meant for the small amounts of data the bot has to persist on its own.
+//
+// Every write rewrites the whole bucket file, so the store holds one lock for all its operations:
+// concurrent writes to the same bucket run one after the other and none of them is lost.
+// Writes through two stores sharing a directory are not coordinated and may overwrite each other.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore creates a store that keeps its files in dir. The directory is created on the first write.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Get decodes the value stored under key in bucket into v. It reports false if there is no such value.
+func (s *FileStore) Get(bucket, key string, v interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.readBucket(bucket)
+	if err != nil {
+		return false, err
+	}
+	raw, ok := values[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return false, fmt.Errorf("decoding %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// Put stores v under key in bucket, replacing the previous value.
+func (s *FileStore) Put(bucket, key string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding %s/%s: %w", bucket, key, err)
+	}
+
+	values, err := s.readBucket(bucket)
+	if err != nil {
+		return err
+	}
+	values[key] = raw
+	return s.writeBucket(bucket, values)
+}
+
+// Delete removes the value stored under key in bucket.
+func (s *FileStore) Delete(bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.readBucket(bucket)
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+	delete(values, key)
+	return s.writeBucket(bucket, values)
+}
+
+// bucketPath returns the path of the file holding the bucket.
+func (s *FileStore) bucketPath(bucket string) string {
+	return filepath.Join(s.dir, bucket+".json")
+}
+
+// readBucket loads all values of a bucket. A missing file is an empty bucket.
+func (s *FileStore) readBucket(bucket string) (map[string]json.RawMessage, error) {
+	values := make(map[string]json.RawMessage)
+
+	data, err := os.ReadFile(s.bucketPath(bucket))
+	if errors.Is(err, os.ErrNotExist) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading bucket %s: %w", bucket, err)
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("decoding bucket %s: %w", bucket, err)
+	}
+	return values, nil
+}
+
+// writeBucket saves all values of a bucket. The file is replaced atomically so a crash never leaves it half written.
+func (s *FileStore) writeBucket(bucket string, values map[string]json.RawMessage) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding bucket %s: %w", bucket, err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, bucket+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing bucket %s: %w", bucket, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing bucket %s: %w", bucket, err)
+	}
+	if err := os.Rename(tmp.Name(), s.bucketPath(bucket)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("replacing bucket %s: %w", bucket, err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestMissingBucket(t *testing.T) {
+	s := NewFileStore(filepath.Join(t.TempDir(), "storage"))
+
+	var value string
+	ok, err := s.Get("missing", "key", &value)
+	if err != nil || ok {
+		t.Errorf("Get = %v, %v; want no value", ok, err)
+	}
+	if err := s.Delete("missing", "key"); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+	if _, err := os.Stat(s.dir); !os.IsNotExist(err) {
+		t.Errorf("Delete on a missing bucket created the directory: %v", err)
+	}
+
+	if err := s.Put("missing", "key", "value"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	ok, err = s.Get("missing", "key", &value)
+	if err != nil || !ok || value != "value" {
+		t.Errorf("Get = %q, %v, %v; want the stored value", value, ok, err)
+	}
+}
+
+func TestPutGetDelete(t *testing.T) {
+	s := NewFileStore(t.TempDir())
+
+	type address struct {
+		Street string
+		Zip    int
+	}
+	want := address{Street: "Main St", Zip: 1000}
+	if err := s.Put("addresses", "1001", want); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Put("addresses", "1002", address{Street: "Side St"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	var got address
+	if ok, err := s.Get("addresses", "1001", &got); err != nil || !ok || got != want {
+		t.Errorf("Get = %+v, %v, %v; want %+v", got, ok, err, want)
+	}
+
+	if err := s.Delete("addresses", "1001"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if ok, err := s.Get("addresses", "1001", &got); err != nil || ok {
+		t.Errorf("Get after Delete = %v, %v; want no value", ok, err)
+	}
+	if ok, err := s.Get("addresses", "1002", &got); err != nil || !ok {
+		t.Errorf("Delete removed the other values of the bucket: %v, %v", ok, err)
+	}
+}
+
+func TestWriteReplacesBucketAtomically(t *testing.T) {
+	dir := t.TempDir()
+	s := NewFileStore(dir)
+
+	if err := s.Put("favorites", "1001", []int{1, 2}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Put("favorites", "1001", []int{3}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "favorites.json" {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("directory holds %v, want only the bucket file", names)
+	}
+
+	// A new store reads what the first one wrote
+	var got []int
+	if ok, err := NewFileStore(dir).Get("favorites", "1001", &got); err != nil || !ok || len(got) != 1 || got[0] != 3 {
+		t.Errorf("Get = %v, %v, %v; want [3]", got, ok, err)
+	}
+}
+
+func TestWriteFailureKeepsBucket(t *testing.T) {
+	dir := t.TempDir()
+	s := NewFileStore(dir)
+
+	if err := s.Put("orders", "1001", "kept"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Put("orders", "1002", func() {}); err == nil {
+		t.Fatal("Put of a value JSON cannot encode succeeded")
+	}
+
+	var got string
+	if ok, err := s.Get("orders", "1001", &got); err != nil || !ok || got != "kept" {
+		t.Errorf("Get = %q, %v, %v; want the value written before the failure", got, ok, err)
+	}
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	s := NewFileStore(t.TempDir())
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprint(i)
+			if err := s.Put("shared", key, i); err != nil {
+				t.Errorf("Put shared/%s: %v", key, err)
+			}
+			if err := s.Put("bucket"+key, key, i); err != nil {
+				t.Errorf("Put bucket%s/%s: %v", key, key, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Each write rewrites the whole bucket, so a lost update would drop the keys of other writers
+	for i := 0; i < writers; i++ {
+		key := fmt.Sprint(i)
+		for _, bucket := range []string{"shared", "bucket" + key} {
+			var got int
+			if ok, err := s.Get(bucket, key, &got); err != nil || !ok || got != i {
+				t.Errorf("Get %s/%s = %d, %v, %v; want %d", bucket, key, got, ok, err, i)
+			}
+		}
+	}
+}